@@ -0,0 +1,255 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: faucet/v1/faucet.proto
+
+package faucetv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// FaucetServiceClient is the client API for FaucetService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type FaucetServiceClient interface {
+	// Claim funds the address directly or adds it to the queue.
+	Claim(ctx context.Context, in *ClaimRequest, opts ...grpc.CallOption) (*ClaimResponse, error)
+	// GetClaim returns the status of a recent claim.
+	GetClaim(ctx context.Context, in *GetClaimRequest, opts ...grpc.CallOption) (*GetClaimResponse, error)
+	// Info returns the faucet account, network and payout.
+	Info(ctx context.Context, in *InfoRequest, opts ...grpc.CallOption) (*InfoResponse, error)
+	// StreamClaims streams the changes of the given claims until they are
+	// confirmed, failed or cancelled, or of every claim if no ids are given.
+	// Every claim is only streamed with an API key or the admin token, and
+	// redacted like the activity feed.
+	StreamClaims(ctx context.Context, in *StreamClaimsRequest, opts ...grpc.CallOption) (FaucetService_StreamClaimsClient, error)
+}
+
+type faucetServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewFaucetServiceClient(cc grpc.ClientConnInterface) FaucetServiceClient {
+	return &faucetServiceClient{cc}
+}
+
+func (c *faucetServiceClient) Claim(ctx context.Context, in *ClaimRequest, opts ...grpc.CallOption) (*ClaimResponse, error) {
+	out := new(ClaimResponse)
+	err := c.cc.Invoke(ctx, "/faucet.v1.FaucetService/Claim", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *faucetServiceClient) GetClaim(ctx context.Context, in *GetClaimRequest, opts ...grpc.CallOption) (*GetClaimResponse, error) {
+	out := new(GetClaimResponse)
+	err := c.cc.Invoke(ctx, "/faucet.v1.FaucetService/GetClaim", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *faucetServiceClient) Info(ctx context.Context, in *InfoRequest, opts ...grpc.CallOption) (*InfoResponse, error) {
+	out := new(InfoResponse)
+	err := c.cc.Invoke(ctx, "/faucet.v1.FaucetService/Info", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *faucetServiceClient) StreamClaims(ctx context.Context, in *StreamClaimsRequest, opts ...grpc.CallOption) (FaucetService_StreamClaimsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &FaucetService_ServiceDesc.Streams[0], "/faucet.v1.FaucetService/StreamClaims", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &faucetServiceStreamClaimsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type FaucetService_StreamClaimsClient interface {
+	Recv() (*StreamClaimsResponse, error)
+	grpc.ClientStream
+}
+
+type faucetServiceStreamClaimsClient struct {
+	grpc.ClientStream
+}
+
+func (x *faucetServiceStreamClaimsClient) Recv() (*StreamClaimsResponse, error) {
+	m := new(StreamClaimsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// FaucetServiceServer is the server API for FaucetService service.
+// All implementations must embed UnimplementedFaucetServiceServer
+// for forward compatibility
+type FaucetServiceServer interface {
+	// Claim funds the address directly or adds it to the queue.
+	Claim(context.Context, *ClaimRequest) (*ClaimResponse, error)
+	// GetClaim returns the status of a recent claim.
+	GetClaim(context.Context, *GetClaimRequest) (*GetClaimResponse, error)
+	// Info returns the faucet account, network and payout.
+	Info(context.Context, *InfoRequest) (*InfoResponse, error)
+	// StreamClaims streams the changes of the given claims until they are
+	// confirmed, failed or cancelled, or of every claim if no ids are given.
+	// Every claim is only streamed with an API key or the admin token, and
+	// redacted like the activity feed.
+	StreamClaims(*StreamClaimsRequest, FaucetService_StreamClaimsServer) error
+	mustEmbedUnimplementedFaucetServiceServer()
+}
+
+// UnimplementedFaucetServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedFaucetServiceServer struct {
+}
+
+func (UnimplementedFaucetServiceServer) Claim(context.Context, *ClaimRequest) (*ClaimResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Claim not implemented")
+}
+func (UnimplementedFaucetServiceServer) GetClaim(context.Context, *GetClaimRequest) (*GetClaimResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetClaim not implemented")
+}
+func (UnimplementedFaucetServiceServer) Info(context.Context, *InfoRequest) (*InfoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Info not implemented")
+}
+func (UnimplementedFaucetServiceServer) StreamClaims(*StreamClaimsRequest, FaucetService_StreamClaimsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamClaims not implemented")
+}
+func (UnimplementedFaucetServiceServer) mustEmbedUnimplementedFaucetServiceServer() {}
+
+// UnsafeFaucetServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to FaucetServiceServer will
+// result in compilation errors.
+type UnsafeFaucetServiceServer interface {
+	mustEmbedUnimplementedFaucetServiceServer()
+}
+
+func RegisterFaucetServiceServer(s grpc.ServiceRegistrar, srv FaucetServiceServer) {
+	s.RegisterService(&FaucetService_ServiceDesc, srv)
+}
+
+func _FaucetService_Claim_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClaimRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FaucetServiceServer).Claim(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/faucet.v1.FaucetService/Claim",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FaucetServiceServer).Claim(ctx, req.(*ClaimRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FaucetService_GetClaim_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetClaimRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FaucetServiceServer).GetClaim(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/faucet.v1.FaucetService/GetClaim",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FaucetServiceServer).GetClaim(ctx, req.(*GetClaimRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FaucetService_Info_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FaucetServiceServer).Info(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/faucet.v1.FaucetService/Info",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FaucetServiceServer).Info(ctx, req.(*InfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FaucetService_StreamClaims_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamClaimsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FaucetServiceServer).StreamClaims(m, &faucetServiceStreamClaimsServer{stream})
+}
+
+type FaucetService_StreamClaimsServer interface {
+	Send(*StreamClaimsResponse) error
+	grpc.ServerStream
+}
+
+type faucetServiceStreamClaimsServer struct {
+	grpc.ServerStream
+}
+
+func (x *faucetServiceStreamClaimsServer) Send(m *StreamClaimsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// FaucetService_ServiceDesc is the grpc.ServiceDesc for FaucetService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var FaucetService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "faucet.v1.FaucetService",
+	HandlerType: (*FaucetServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Claim",
+			Handler:    _FaucetService_Claim_Handler,
+		},
+		{
+			MethodName: "GetClaim",
+			Handler:    _FaucetService_GetClaim_Handler,
+		},
+		{
+			MethodName: "Info",
+			Handler:    _FaucetService_Info_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamClaims",
+			Handler:       _FaucetService_StreamClaims_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "faucet/v1/faucet.proto",
+}
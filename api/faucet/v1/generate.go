@@ -0,0 +1,4 @@
+// Package faucetv1 contains the gRPC service of the faucet.
+package faucetv1
+
+//go:generate sh -c "cd ../.. && buf generate"
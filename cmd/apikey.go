@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/chainflag/eth-faucet/internal/apikey"
+)
+
+const apiKeyUsage = "usage: eth-faucet -apikey.file <file> apikey create|list|revoke"
+
+func runAPIKeyCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New(apiKeyUsage)
+	}
+	if cfg.APIKey.File == "" {
+		return errors.New("missing -apikey.file flag")
+	}
+	store, err := apikey.Open(cfg.APIKey.File)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "create":
+		return createAPIKey(store, args[1:])
+	case "list":
+		return listAPIKeys(store)
+	case "revoke":
+		if len(args) != 2 {
+			return errors.New("usage: eth-faucet apikey revoke <id>")
+		}
+		if err := store.Revoke(args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("Revoked API key %s\n", args[1])
+		return nil
+	default:
+		return errors.New(apiKeyUsage)
+	}
+}
+
+func createAPIKey(store *apikey.Store, args []string) error {
+	fs := flag.NewFlagSet("apikey create", flag.ContinueOnError)
+	name := fs.String("name", "", "Name of the key owner")
+	quota := fs.Int("quota", 0, "Number of claims allowed per window, 0 for unlimited")
+	window := fs.Int("window", 1440, "Number of minutes in a quota window")
+	payout := fs.Int("payout", 0, "Number of Ethers to transfer per claim, 0 for the faucet default")
+	class := fs.String("class", "", "Priority class to queue claims in")
+	tokens := fs.String("tokens", "", "Comma separated tokens the key may claim, empty for all")
+	expires := fs.Duration("expires", 0, "Time until the key expires, 0 for never")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *name == "" {
+		return errors.New("missing -name flag")
+	}
+
+	key := apikey.Key{
+		Name:   *name,
+		Quota:  *quota,
+		Window: *window,
+		Payout: *payout,
+		Class:  *class,
+	}
+	if *tokens != "" {
+		for _, token := range strings.Split(*tokens, ",") {
+			key.Tokens = append(key.Tokens, strings.TrimSpace(token))
+		}
+	}
+	if *expires > 0 {
+		expiresAt := time.Now().Add(*expires).UTC()
+		key.ExpiresAt = &expiresAt
+	}
+
+	secret, created, err := store.Create(key)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created API key %s for %s\n", created.ID, created.Name)
+	fmt.Printf("Secret (shown only once): %s\n", secret)
+	return nil
+}
+
+func listAPIKeys(store *apikey.Store) error {
+	keys, err := store.List()
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tQUOTA\tWINDOW\tPAYOUT\tCLASS\tTOKENS\tEXPIRES")
+	for _, key := range keys {
+		expires := "never"
+		if key.ExpiresAt != nil {
+			expires = key.ExpiresAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%dm\t%d\t%s\t%s\t%s\n", key.ID, key.Name, key.Quota, key.Window,
+			key.Payout, key.Class, strings.Join(key.Tokens, ","), expires)
+	}
+	return tw.Flush()
+}
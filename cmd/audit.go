@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/chainflag/eth-faucet/internal/audit"
+)
+
+const auditUsage = "usage: eth-faucet -audit.file <file> audit verify|export"
+
+func runAuditCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New(auditUsage)
+	}
+	if cfg.Audit.File == "" {
+		return errors.New("missing -audit.file flag")
+	}
+	f, err := os.Open(cfg.Audit.File)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch args[0] {
+	case "verify":
+		count, err := audit.Verify(f)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Verified %d audit records\n", count)
+		return nil
+	case "export":
+		return exportAudit(f, args[1:])
+	default:
+		return errors.New(auditUsage)
+	}
+}
+
+func exportAudit(f *os.File, args []string) error {
+	fs := flag.NewFlagSet("audit export", flag.ContinueOnError)
+	from := fs.String("from", "", "Export records from this time on, as RFC 3339 or YYYY-MM-DD")
+	to := fs.String("to", "", "Export records before this time, as RFC 3339 or YYYY-MM-DD")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	fromTime, err := parseTime(*from)
+	if err != nil {
+		return fmt.Errorf("invalid -from: %w", err)
+	}
+	toTime, err := parseTime(*to)
+	if err != nil {
+		return fmt.Errorf("invalid -to: %w", err)
+	}
+
+	count, err := audit.ExportCSV(os.Stdout, f, fromTime, toTime)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d audit records\n", count)
+	return nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
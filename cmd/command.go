@@ -0,0 +1,16 @@
+package cmd
+
+import (
+	"fmt"
+)
+
+func runCommand(args []string) error {
+	switch args[0] {
+	case "apikey":
+		return runAPIKeyCommand(args[1:])
+	case "audit":
+		return runAuditCommand(args[1:])
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
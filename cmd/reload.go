@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/chainflag/eth-faucet/internal/server"
+)
+
+const configPollInterval = 5 * time.Second
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFile(path string) fileStamp {
+	if path == "" {
+		return fileStamp{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+// statFiles stamps the config file and the classes files of every network.
+func statFiles(c *server.Config) map[string]fileStamp {
+	paths := []string{*configFlag, c.Faucet.ClassesFile}
+	for _, n := range c.Networks {
+		paths = append(paths, n.Faucet.ClassesFile)
+	}
+	stamps := make(map[string]fileStamp, len(paths))
+	for _, path := range paths {
+		if path != "" {
+			stamps[path] = statFile(path)
+		}
+	}
+	return stamps
+}
+
+// filesChanged reports whether any of the stamped files changed on disk.
+func filesChanged(stamps map[string]fileStamp) bool {
+	for path, stamp := range stamps {
+		if statFile(path) != stamp {
+			return true
+		}
+	}
+	return false
+}
+
+// readConfig builds the config again from the defaults, the config file and
+// the overrides given at startup.
+func readConfig() (*server.Config, error) {
+	next := server.DefaultConfig()
+	if *configFlag != "" {
+		if err := next.LoadFile(*configFlag); err != nil {
+			return nil, err
+		}
+	}
+	fs := flag.NewFlagSet("reload", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	bindFlags(fs, next)
+	for name, value := range overrides {
+		if fs.Lookup(name) == nil {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return nil, fmt.Errorf("invalid value %q for %s: %w", value, name, err)
+		}
+	}
+
+	if err := loadClasses(next); err != nil {
+		return nil, err
+	}
+	if err := next.Validate(); err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+// watchConfig reloads the config on SIGHUP and whenever the config file or
+// the classes file of any network changes on disk.
+func watchConfig(ctx context.Context, srv *server.Server) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+
+	stamps := statFiles(cfg)
+	reload := func(reason string) {
+		// A failed reload is not retried until the files change again
+		for path := range stamps {
+			stamps[path] = statFile(path)
+		}
+		next, err := readConfig()
+		if err == nil {
+			err = srv.Reload(next)
+		}
+		if err != nil {
+			log.WithError(err).WithField("trigger", reason).Error("Failed to reload config")
+			return
+		}
+		stamps = statFiles(next)
+	}
+
+	for {
+		select {
+		case <-hup:
+			reload("signal")
+		case <-ticker.C:
+			if filesChanged(stamps) {
+				reload("file change")
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
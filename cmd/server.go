@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"errors"
 	"flag"
@@ -9,8 +10,11 @@ import (
 	"os"
 	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/ethereum/go-ethereum/crypto"
+	log "github.com/sirupsen/logrus"
 
 	"github.com/chainflag/eth-faucet/internal/chain"
 	"github.com/chainflag/eth-faucet/internal/server"
@@ -18,24 +22,26 @@ import (
 
 var (
 	appVersion = "v1.1.0"
-	chainIDMap = map[string]int{"goerli": 5, "sepolia": 11155111}
 
-	httpPortFlag = flag.Int("httpport", 8080, "Listener port to serve HTTP connection")
-	proxyCntFlag = flag.Int("proxycount", 0, "Count of reverse proxies in front of the server")
-	queueCapFlag = flag.Int("queuecap", 100, "Maximum transactions waiting to be sent")
-	versionFlag  = flag.Bool("version", false, "Print version number")
+	cfg = server.DefaultConfig()
 
-	payoutFlag   = flag.Int("faucet.amount", 1, "Number of Ethers to transfer per user request")
-	intervalFlag = flag.Int("faucet.minutes", 1440, "Number of minutes to wait between funding rounds")
-	netnameFlag  = flag.String("faucet.name", "testnet", "Network name to display on the frontend")
+	// overrides holds the settings given by environment variables and flags,
+	// which take precedence over the config file
+	overrides = make(map[string]string)
 
-	keyJSONFlag  = flag.String("wallet.keyjson", os.Getenv("KEYSTORE"), "Keystore file to fund user requests with")
-	keyPassFlag  = flag.String("wallet.keypass", "password.txt", "Passphrase text file to decrypt keystore")
-	privKeyFlag  = flag.String("wallet.privkey", os.Getenv("PRIVATE_KEY"), "Private key hex to fund user requests with")
-	providerFlag = flag.String("wallet.provider", os.Getenv("WEB3_PROVIDER"), "Endpoint for Ethereum JSON-RPC connection")
+	configFlag  = flag.String("config", "", "YAML or TOML file to read the configuration from")
+	versionFlag = flag.Bool("version", false, "Print version number")
+
+	// legacyEnv maps the environment variables of earlier versions to their flags
+	legacyEnv = map[string]string{
+		"wallet.keyjson":  "KEYSTORE",
+		"wallet.privkey":  "PRIVATE_KEY",
+		"wallet.provider": "WEB3_PROVIDER",
+	}
 )
 
 func init() {
+	bindFlags(flag.CommandLine, cfg)
 	flag.Parse()
 	if *versionFlag {
 		fmt.Println(appVersion)
@@ -43,44 +49,239 @@ func init() {
 	}
 }
 
+// bindFlags registers the flags of all settings, storing their values in cfg.
+func bindFlags(fs *flag.FlagSet, cfg *server.Config) {
+	fs.StringVar(&cfg.HTTPAddr, "httpaddr", cfg.HTTPAddr, "Interface to serve HTTP connection on, all if empty, or unix:/path for a Unix socket")
+	fs.IntVar(&cfg.HTTPPort, "httpport", cfg.HTTPPort, "Listener port to serve HTTP connection")
+	fs.IntVar(&cfg.ProxyCount, "proxycount", cfg.ProxyCount, "Count of reverse proxies in front of the server")
+	fs.IntVar(&cfg.QueueCap, "queuecap", cfg.QueueCap, "Maximum transactions waiting to be sent in each queue lane")
+	fs.StringVar(&cfg.QueueFile, "queuefile", cfg.QueueFile, "File to persist queued transactions to on shutdown")
+	fs.IntVar(&cfg.ShutdownTimeout, "shutdowntimeout", cfg.ShutdownTimeout, "Number of seconds to wait for the queue to drain on shutdown")
+
+	fs.IntVar(&cfg.Faucet.Amount, "faucet.amount", cfg.Faucet.Amount, "Number of Ethers to transfer per user request")
+	fs.IntVar(&cfg.Faucet.Minutes, "faucet.minutes", cfg.Faucet.Minutes, "Number of minutes to wait between funding rounds")
+	fs.StringVar(&cfg.Faucet.Name, "faucet.name", cfg.Faucet.Name, "Network name to display on the frontend")
+	fs.StringVar(&cfg.Faucet.ClassesFile, "faucet.classes", cfg.Faucet.ClassesFile, "JSON file describing priority classes of claimants")
+	fs.StringVar(&cfg.APIKey.File, "apikey.file", cfg.APIKey.File, "JSON file to store hashed API keys in")
+
+	fs.StringVar(&cfg.Admin.Addr, "admin.addr", cfg.Admin.Addr, "Interface to serve the admin API on, all if empty, or unix:/path for a Unix socket")
+	fs.IntVar(&cfg.Admin.Port, "admin.port", cfg.Admin.Port, "Listener port to serve the admin API, 0 to disable it")
+	fs.StringVar(&cfg.Admin.Token, "admin.token", cfg.Admin.Token, "Bearer token required by the admin API")
+	fs.StringVar(&cfg.Feed.Redact, "feed.redact", cfg.Feed.Redact, "Redaction of addresses in the activity feed: none, partial or full")
+	fs.StringVar(&cfg.GRPC.Addr, "grpc.addr", cfg.GRPC.Addr, "Interface to serve the gRPC API on, all if empty, or unix:/path for a Unix socket")
+	fs.IntVar(&cfg.GRPC.Port, "grpc.port", cfg.GRPC.Port, "Listener port to serve the gRPC API, 0 to disable it")
+	fs.StringVar(&cfg.Metrics.Addr, "metrics.addr", cfg.Metrics.Addr, "Interface to serve metrics on, all if empty, or unix:/path for a Unix socket")
+	fs.IntVar(&cfg.Metrics.Port, "metrics.port", cfg.Metrics.Port, "Listener port to serve metrics on instead of the HTTP port, 0 to disable it")
+
+	fs.StringVar(&cfg.Monitor.Webhooks, "monitor.webhooks", cfg.Monitor.Webhooks, "Comma separated URLs to post balance alerts to")
+	fs.StringVar(&cfg.Monitor.Thresholds, "monitor.thresholds", cfg.Monitor.Thresholds, "Comma separated balances in Ether to alert below")
+	fs.Float64Var(&cfg.Monitor.Floor, "monitor.floor", cfg.Monitor.Floor, "Balance in Ether below which claims are paused, 0 to disable it")
+	fs.StringVar(&cfg.Tracing.Endpoint, "tracing.endpoint", cfg.Tracing.Endpoint, "OTLP/HTTP endpoint to export traces to, empty to disable tracing")
+	fs.Float64Var(&cfg.Tracing.Ratio, "tracing.ratio", cfg.Tracing.Ratio, "Ratio of new traces to sample between 0 and 1")
+	fs.StringVar(&cfg.Audit.File, "audit.file", cfg.Audit.File, "JSONL file to append the hash-chained audit log of payouts to")
+	fs.StringVar(&cfg.Audit.Salt, "audit.salt", cfg.Audit.Salt, "Secret to key the hashes of client IPs in the audit log with, required with -audit.file")
+	fs.StringVar(&cfg.TLS.CertFile, "tls.certfile", cfg.TLS.CertFile, "PEM certificate file to serve HTTPS with")
+	fs.StringVar(&cfg.TLS.KeyFile, "tls.keyfile", cfg.TLS.KeyFile, "PEM private key file of the certificate")
+	fs.StringVar(&cfg.TLS.Domains, "tls.domains", cfg.TLS.Domains, "Comma separated domains to obtain certificates for over ACME")
+	fs.StringVar(&cfg.TLS.Email, "tls.email", cfg.TLS.Email, "Contact email of the ACME account")
+	fs.StringVar(&cfg.TLS.Directory, "tls.directory", cfg.TLS.Directory, "Directory URL of the ACME certificate authority")
+	fs.StringVar(&cfg.TLS.CacheDir, "tls.cachedir", cfg.TLS.CacheDir, "Directory to cache ACME certificates and the account key in")
+	fs.IntVar(&cfg.TLS.RedirectPort, "tls.redirectport", cfg.TLS.RedirectPort, "Listener port to redirect HTTP to HTTPS and answer ACME challenges on, 0 to disable it")
+	fs.IntVar(&cfg.HTTP.ReadTimeout, "http.readtimeout", cfg.HTTP.ReadTimeout, "Number of seconds to read a request in, 0 for no limit")
+	fs.IntVar(&cfg.HTTP.ReadHeaderTimeout, "http.readheadertimeout", cfg.HTTP.ReadHeaderTimeout, "Number of seconds to read the request headers in, 0 for no limit")
+	fs.IntVar(&cfg.HTTP.WriteTimeout, "http.writetimeout", cfg.HTTP.WriteTimeout, "Number of seconds to write a response in, 0 for no limit")
+	fs.IntVar(&cfg.HTTP.IdleTimeout, "http.idletimeout", cfg.HTTP.IdleTimeout, "Number of seconds to keep idle connections open, 0 for no limit")
+	fs.StringVar(&cfg.Stats.File, "stats.file", cfg.Stats.File, "JSONL file to keep the claim history for the public statistics in")
+	fs.StringVar(&cfg.Log.Format, "log.format", cfg.Log.Format, "Log format: text or json")
+	fs.StringVar(&cfg.Log.Level, "log.level", cfg.Log.Level, "Minimum log level: trace, debug, info, warn or error")
+
+	fs.StringVar(&cfg.Wallet.KeyJSON, "wallet.keyjson", cfg.Wallet.KeyJSON, "Keystore file to fund user requests with")
+	fs.StringVar(&cfg.Wallet.KeyPass, "wallet.keypass", cfg.Wallet.KeyPass, "Passphrase text file to decrypt keystore")
+	fs.StringVar(&cfg.Wallet.PrivKey, "wallet.privkey", cfg.Wallet.PrivKey, "Private key hex to fund user requests with")
+	fs.StringVar(&cfg.Wallet.Provider, "wallet.provider", cfg.Wallet.Provider, "Comma separated endpoints for Ethereum JSON-RPC connections")
+	fs.Int64Var(&cfg.Wallet.ChainID, "wallet.chainid", cfg.Wallet.ChainID, "Chain ID the provider must report, 0 to derive it from the network name")
+}
+
+// envName returns the environment variable of a flag, which is prefixed with
+// FAUCET_ to keep clear of unrelated variables, e.g. FAUCET_HTTPPORT for
+// -httpport or FAUCET_ADMIN_TOKEN for -admin.token. The flags of the faucet
+// group are not prefixed twice, e.g. FAUCET_AMOUNT for -faucet.amount.
+func envName(name string) string {
+	name = strings.TrimPrefix(name, "faucet.")
+	return "FAUCET_" + strings.ToUpper(strings.ReplaceAll(name, ".", "_"))
+}
+
+// loadConfig layers the config file, environment variables and flags over
+// the defaults, each taking precedence over the previous one.
+func loadConfig() error {
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	var err error
+	flag.VisitAll(func(f *flag.Flag) {
+		if given[f.Name] || f.Name == "version" || err != nil {
+			return
+		}
+		value, ok := os.LookupEnv(envName(f.Name))
+		if !ok && legacyEnv[f.Name] != "" {
+			value, ok = os.LookupEnv(legacyEnv[f.Name])
+		}
+		if ok {
+			if serr := flag.Set(f.Name, value); serr != nil {
+				err = fmt.Errorf("invalid value %q for %s: %w", value, envName(f.Name), serr)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name != "config" {
+			overrides[f.Name] = f.Value.String()
+		}
+	})
+	if *configFlag != "" {
+		if err := cfg.LoadFile(*configFlag); err != nil {
+			return err
+		}
+		for name, value := range overrides {
+			flag.Set(name, value)
+		}
+	}
+
+	return loadClasses(cfg)
+}
+
+// loadClasses appends the classes of the classes files to the inline ones.
+func loadClasses(cfg *server.Config) error {
+	classes, err := server.LoadClaimClasses(cfg.Faucet.ClassesFile)
+	if err != nil {
+		return fmt.Errorf("failed to load claim classes: %w", err)
+	}
+	cfg.Faucet.Classes = append(cfg.Faucet.Classes, classes...)
+	for i := range cfg.Networks {
+		n := &cfg.Networks[i]
+		classes, err := server.LoadClaimClasses(n.Faucet.ClassesFile)
+		if err != nil {
+			return fmt.Errorf("failed to load claim classes of network %s: %w", n.Name, err)
+		}
+		n.Faucet.Classes = append(n.Faucet.Classes, classes...)
+	}
+	return nil
+}
+
 func Execute() {
-	privateKey, err := getPrivateKeyFromFlags()
+	if err := loadConfig(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if flag.NArg() > 0 {
+		if err := runCommand(flag.Args()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	cfg.Feed.Redact = strings.ToLower(cfg.Feed.Redact)
+	setupLogging(cfg.Log)
+
+	txBuilder, err := newTxBuilder(cfg)
 	if err != nil {
-		panic(fmt.Errorf("failed to read private key: %w", err))
+		panic(err)
 	}
-	var chainID *big.Int
-	if value, ok := chainIDMap[strings.ToLower(*netnameFlag)]; ok {
-		chainID = big.NewInt(int64(value))
+	srv, err := server.NewServer(txBuilder, cfg)
+	if err != nil {
+		panic(err)
+	}
+	for _, n := range cfg.Networks {
+		networkCfg := cfg.ForNetwork(n)
+		txBuilder, err := newTxBuilder(networkCfg)
+		if err != nil {
+			panic(fmt.Errorf("network %s: %w", n.Name, err))
+		}
+		network, err := server.NewServer(txBuilder, networkCfg)
+		if err != nil {
+			panic(fmt.Errorf("network %s: %w", n.Name, err))
+		}
+		srv.AddNetwork(n.Name, network)
 	}
 
-	txBuilder, err := chain.NewTxBuilder(*providerFlag, privateKey, chainID)
+	shutdownTracing, err := setupTracing(cfg.Tracing)
 	if err != nil {
-		panic(fmt.Errorf("cannot connect to web3 provider: %w", err))
+		panic(fmt.Errorf("failed to set up tracing: %w", err))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := srv.Start(ctx); err != nil {
+		panic(fmt.Errorf("failed to start server: %w", err))
 	}
-	config := server.NewConfig(*netnameFlag, *httpPortFlag, *intervalFlag, *payoutFlag, *proxyCntFlag, *queueCapFlag)
-	go server.NewServer(txBuilder, config).Run()
+	go watchConfig(ctx, srv)
+	<-ctx.Done()
+	stop()
 
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-	<-c
+	log.Info("Shutting down, waiting for queued transactions to drain")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeout)*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.WithError(err).Error("Failed to shut down gracefully")
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.WithError(err).Warn("Failed to flush traces")
+	}
+}
+
+// setupLogging applies the log format and level of a validated config.
+func setupLogging(lc server.LogConfig) {
+	if strings.ToLower(lc.Format) == "json" {
+		log.SetFormatter(&log.JSONFormatter{})
+	} else {
+		log.SetFormatter(&log.TextFormatter{})
+	}
+	if level, err := log.ParseLevel(lc.Level); err == nil {
+		log.SetLevel(level)
+	}
+}
+
+// newTxBuilder connects to the provider, which must be on the configured
+// chain. The chain ID is taken from the network name if it is not set.
+func newTxBuilder(cfg *server.Config) (chain.TxBuilder, error) {
+	privateKey, err := getPrivateKey(cfg.Wallet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+	var chainID *big.Int
+	if id := cfg.ChainID(); id > 0 {
+		chainID = big.NewInt(id)
+	}
+	txBuilder, err := chain.NewTxBuilder(cfg.Wallet.Provider, privateKey, chainID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to web3 provider %s: %w", cfg.Wallet.Provider, err)
+	}
+	return txBuilder, nil
 }
 
-func getPrivateKeyFromFlags() (*ecdsa.PrivateKey, error) {
-	if *privKeyFlag != "" {
-		hexkey := *privKeyFlag
+func getPrivateKey(wallet server.WalletConfig) (*ecdsa.PrivateKey, error) {
+	if wallet.PrivKey != "" {
+		hexkey := wallet.PrivKey
 		if chain.Has0xPrefix(hexkey) {
 			hexkey = hexkey[2:]
 		}
 		return crypto.HexToECDSA(hexkey)
-	} else if *keyJSONFlag == "" {
+	} else if wallet.KeyJSON == "" {
 		return nil, errors.New("missing private key or keystore")
 	}
 
-	keyfile, err := chain.ResolveKeyfilePath(*keyJSONFlag)
+	keyfile, err := chain.ResolveKeyfilePath(wallet.KeyJSON)
 	if err != nil {
 		return nil, err
 	}
-	password, err := os.ReadFile(*keyPassFlag)
+	password, err := os.ReadFile(wallet.KeyPass)
 	if err != nil {
 		return nil, err
 	}
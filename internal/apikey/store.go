@@ -0,0 +1,230 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	secretPrefix = "faucet_"
+	NativeToken  = "ETH"
+)
+
+var (
+	ErrInvalidKey = errors.New("invalid API key")
+	ErrExpiredKey = errors.New("API key has expired")
+	ErrNotFound   = errors.New("API key not found")
+)
+
+// Key describes an API key. Only the SHA-256 hash of the secret is stored.
+type Key struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Hash      string     `json:"hash"`
+	Quota     int        `json:"quota"`
+	Window    int        `json:"window"`
+	Payout    int        `json:"payout"`
+	Tokens    []string   `json:"tokens,omitempty"`
+	Class     string     `json:"class,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+func (k *Key) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && now.After(*k.ExpiresAt)
+}
+
+// AllowsToken reports whether the key may claim the token. An empty list allows every token.
+func (k *Key) AllowsToken(symbol string) bool {
+	if len(k.Tokens) == 0 {
+		return true
+	}
+	for _, token := range k.Tokens {
+		if strings.EqualFold(token, symbol) {
+			return true
+		}
+	}
+	return false
+}
+
+// QuotaWindow is the period in which at most Quota claims are allowed.
+func (k *Key) QuotaWindow() time.Duration {
+	return time.Duration(k.Window) * time.Minute
+}
+
+// Store is a JSON file of hashed API keys. It picks up changes made to the
+// file by other processes, such as the apikey subcommand.
+type Store struct {
+	mutex   sync.RWMutex
+	path    string
+	modTime time.Time
+	size    int64
+	keys    map[string]*Key
+}
+
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, keys: make(map[string]*Key)}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) reload() error {
+	info, err := os.Stat(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.keys = make(map[string]*Key)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	var list []*Key
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", s.path, err)
+	}
+	keys := make(map[string]*Key, len(list))
+	for _, key := range list {
+		keys[key.Hash] = key
+	}
+	s.keys = keys
+	s.modTime, s.size = info.ModTime(), info.Size()
+	return nil
+}
+
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.list(), "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".apikeys-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime, s.size = info.ModTime(), info.Size()
+	}
+	return nil
+}
+
+func (s *Store) list() []Key {
+	keys := make([]Key, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, *key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys
+}
+
+func (s *Store) List() ([]Key, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s.list(), nil
+}
+
+// Create stores a new key and returns its secret, which cannot be recovered later.
+func (s *Store) Create(key Key) (string, *Key, error) {
+	id, err := randomHex(4)
+	if err != nil {
+		return "", nil, err
+	}
+	random, err := randomHex(24)
+	if err != nil {
+		return "", nil, err
+	}
+	secret := secretPrefix + id + "_" + random
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.reload(); err != nil {
+		return "", nil, err
+	}
+	key.ID = id
+	key.Hash = hashSecret(secret)
+	key.CreatedAt = time.Now().UTC()
+	s.keys[key.Hash] = &key
+	if err := s.save(); err != nil {
+		delete(s.keys, key.Hash)
+		return "", nil, err
+	}
+	return secret, &key, nil
+}
+
+func (s *Store) Revoke(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.reload(); err != nil {
+		return err
+	}
+	for hash, key := range s.keys {
+		if key.ID == id {
+			delete(s.keys, hash)
+			return s.save()
+		}
+	}
+	return ErrNotFound
+}
+
+// Authenticate looks up the key that belongs to the secret.
+func (s *Store) Authenticate(secret string) (*Key, error) {
+	s.mutex.Lock()
+	if err := s.reload(); err != nil {
+		s.mutex.Unlock()
+		return nil, err
+	}
+	key, ok := s.keys[hashSecret(secret)]
+	s.mutex.Unlock()
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	if key.Expired(time.Now()) {
+		return nil, ErrExpiredKey
+	}
+	return key, nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
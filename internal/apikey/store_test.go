@@ -0,0 +1,79 @@
+package apikey
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apikeys.json")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	expired := time.Now().Add(-time.Hour)
+	secret, key, err := store.Create(Key{Name: "ci", Quota: 10, Window: 60})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	expiredSecret, _, err := store.Create(Key{Name: "old", ExpiresAt: &expired})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// A second store sees the keys written by the first one
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	tests := []struct {
+		name    string
+		secret  string
+		wantErr error
+	}{
+		{name: "valid", secret: secret, wantErr: nil},
+		{name: "expired", secret: expiredSecret, wantErr: ErrExpiredKey},
+		{name: "unknown", secret: "faucet_unknown", wantErr: ErrInvalidKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := reopened.Authenticate(tt.secret)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Authenticate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && got.ID != key.ID {
+				t.Errorf("Authenticate() got key %s, want %s", got.ID, key.ID)
+			}
+		})
+	}
+
+	if err := reopened.Revoke(key.ID); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if _, err := store.Authenticate(secret); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Authenticate() after revoke error = %v, want %v", err, ErrInvalidKey)
+	}
+}
+
+func TestKeyAllowsToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		tokens []string
+		want   bool
+	}{
+		{name: "any", tokens: nil, want: true},
+		{name: "allowed", tokens: []string{"eth"}, want: true},
+		{name: "denied", tokens: []string{"USDC"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := &Key{Tokens: tt.tokens}
+			if got := key.AllowsToken(NativeToken); got != tt.want {
+				t.Errorf("AllowsToken() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
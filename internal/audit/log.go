@@ -0,0 +1,258 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	OutcomeSent   = "sent"
+	OutcomeFailed = "failed"
+
+	// genesisHash is the previous hash of the first record
+	genesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+)
+
+// ErrBrokenChain is returned when a record does not match its hash or the hash
+// of the record before it.
+var ErrBrokenChain = errors.New("audit chain is broken")
+
+// Record is a payout. Each record carries the hash of the one before it, so
+// that changing, removing or reordering records breaks the chain.
+type Record struct {
+	Seq          uint64    `json:"seq"`
+	Time         time.Time `json:"time"`
+	Network      string    `json:"network,omitempty"`
+	ClaimID      string    `json:"claimId"`
+	Recipient    string    `json:"recipient"`
+	Amount       string    `json:"amount"`
+	TxHash       string    `json:"txHash,omitempty"`
+	ClientIPHash string    `json:"clientIpHash,omitempty"`
+	Identity     string    `json:"identity"`
+	Outcome      string    `json:"outcome"`
+	Error        string    `json:"error,omitempty"`
+	PrevHash     string    `json:"prevHash"`
+	Hash         string    `json:"hash,omitempty"`
+}
+
+// digest hashes the record without its own hash.
+func (r Record) digest() (string, error) {
+	r.Hash = ""
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Log appends records to a JSONL file.
+type Log struct {
+	mutex sync.Mutex
+	path  string
+	file  *os.File
+	salt  []byte
+	seq   uint64
+	last  string
+}
+
+// Open opens the log at path and continues its chain, which is verified
+// first. The salt keys the hashes of client IPs, which cannot be reversed
+// without it.
+func Open(path, salt string) (*Log, error) {
+	l := &Log{path: path, salt: []byte(salt), last: genesisHash}
+	if f, err := os.Open(path); err == nil {
+		err = l.resume(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify %s: %w", path, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	l.file = file
+	return l, nil
+}
+
+// resume verifies the records of the file and continues after the last one.
+func (l *Log) resume(f *os.File) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	// A record cut off by a crash or a full disk would be followed on the same line
+	if info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, info.Size()-1); err != nil {
+			return err
+		}
+		if last[0] != '\n' {
+			return errors.New("the last record is incomplete")
+		}
+	}
+	l.seq, l.last, err = verify(f)
+	return err
+}
+
+// HashIP returns the salted hash of a client IP.
+func (l *Log) HashIP(ip string) string {
+	if ip == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, l.salt)
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Append chains the record to the previous one and writes it to disk.
+func (l *Log) Append(r Record) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	r.Seq = l.seq + 1
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	r.Time = r.Time.UTC()
+	r.PrevHash = l.last
+	hash, err := r.digest()
+	if err != nil {
+		return err
+	}
+	r.Hash = hash
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if err := l.file.Sync(); err != nil {
+		return err
+	}
+	l.seq, l.last = r.Seq, r.Hash
+	return nil
+}
+
+// Check reports whether the file is still in place. Records appended to a
+// file that was removed or moved away would be lost.
+func (l *Log) Check() error {
+	info, err := os.Stat(l.path)
+	if err != nil {
+		return err
+	}
+	open, err := l.file.Stat()
+	if err != nil {
+		return err
+	}
+	if !os.SameFile(info, open) {
+		return fmt.Errorf("%s was replaced", l.path)
+	}
+	return nil
+}
+
+func (l *Log) Close() error {
+	return l.file.Close()
+}
+
+// Read calls fn for every record in order.
+func Read(r io.Reader, fn func(Record) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// Verify checks the chain of records and returns the number of records.
+func Verify(r io.Reader) (uint64, error) {
+	count, _, err := verify(r)
+	return count, err
+}
+
+// verify checks the chain of records and returns the number of records and
+// the hash of the last one.
+func verify(r io.Reader) (uint64, string, error) {
+	var count uint64
+	prev := genesisHash
+	err := Read(r, func(record Record) error {
+		count++
+		hash, err := record.digest()
+		switch {
+		case err != nil:
+			return err
+		case record.Seq != count:
+			return fmt.Errorf("%w: record %d has sequence number %d", ErrBrokenChain, count, record.Seq)
+		case record.PrevHash != prev:
+			return fmt.Errorf("%w: record %d does not follow record %d", ErrBrokenChain, record.Seq, count-1)
+		case record.Hash != hash:
+			return fmt.Errorf("%w: record %d was modified", ErrBrokenChain, record.Seq)
+		}
+		prev = record.Hash
+		return nil
+	})
+	return count, prev, err
+}
+
+var csvHeader = []string{"seq", "time", "network", "claim_id", "recipient", "amount", "tx_hash", "client_ip_hash", "identity", "outcome", "error", "hash"}
+
+// ExportCSV writes the records from the start time up to the end time as CSV.
+// Zero times leave the range open.
+func ExportCSV(w io.Writer, r io.Reader, from, to time.Time) (int, error) {
+	out := csv.NewWriter(w)
+	if err := out.Write(csvHeader); err != nil {
+		return 0, err
+	}
+	var count int
+	err := Read(r, func(record Record) error {
+		if (!from.IsZero() && record.Time.Before(from)) || (!to.IsZero() && !record.Time.Before(to)) {
+			return nil
+		}
+		count++
+		return out.Write([]string{
+			strconv.FormatUint(record.Seq, 10),
+			record.Time.Format(time.RFC3339Nano),
+			record.Network,
+			record.ClaimID,
+			record.Recipient,
+			record.Amount,
+			record.TxHash,
+			record.ClientIPHash,
+			record.Identity,
+			record.Outcome,
+			record.Error,
+			record.Hash,
+		})
+	})
+	if err != nil {
+		return count, err
+	}
+	out.Flush()
+	return count, out.Error()
+}
@@ -0,0 +1,137 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeRecords(t *testing.T, path string, times ...time.Time) {
+	t.Helper()
+	l, err := Open(path, "salt")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer l.Close()
+	for _, at := range times {
+		record := Record{Time: at, Recipient: "0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B", Amount: "1000", Outcome: OutcomeSent}
+		if err := l.Append(record); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+}
+
+func TestLogChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeRecords(t, path, start, start.Add(time.Hour))
+	// Reopening continues the chain
+	writeRecords(t, path, start.Add(2*time.Hour))
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count, err := Verify(bytes.NewReader(data)); err != nil || count != 3 {
+		t.Fatalf("Verify() = %d, %v, want 3 records", count, err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	tests := []struct {
+		name  string
+		lines []string
+	}{
+		{name: "modified", lines: []string{lines[0], strings.Replace(lines[1], `"amount":"1000"`, `"amount":"9000"`, 1), lines[2]}},
+		{name: "removed", lines: []string{lines[0], lines[2]}},
+		{name: "reordered", lines: []string{lines[1], lines[0], lines[2]}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Verify(strings.NewReader(strings.Join(tt.lines, "\n")))
+			if !errors.Is(err, ErrBrokenChain) {
+				t.Errorf("Verify() error = %v, want %v", err, ErrBrokenChain)
+			}
+		})
+	}
+}
+
+func TestHashIP(t *testing.T) {
+	a, _ := Open(filepath.Join(t.TempDir(), "a.jsonl"), "one")
+	b, _ := Open(filepath.Join(t.TempDir(), "b.jsonl"), "two")
+	defer a.Close()
+	defer b.Close()
+	if a.HashIP("192.0.2.1") == b.HashIP("192.0.2.1") || a.HashIP("192.0.2.1") != a.HashIP("192.0.2.1") {
+		t.Error("HashIP() is not keyed by the salt")
+	}
+	if a.HashIP("") != "" {
+		t.Error("HashIP() of an unknown IP is not empty")
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeRecords(t, path, start, start.Add(24*time.Hour), start.Add(48*time.Hour))
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var out bytes.Buffer
+	count, err := ExportCSV(&out, f, start.Add(time.Hour), start.Add(48*time.Hour))
+	if err != nil || count != 1 {
+		t.Fatalf("ExportCSV() = %d, %v, want 1 record", count, err)
+	}
+	rows, err := csv.NewReader(&out).ReadAll()
+	if err != nil || len(rows) != 2 || rows[1][0] != "2" || rows[1][1] != "2024-01-02T00:00:00Z" {
+		t.Errorf("ExportCSV() rows = %v, want the header and record 2", rows)
+	}
+}
+
+func TestLogCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := Open(path, "salt")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer l.Close()
+	if err := l.Check(); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+	os.Rename(path, path+".1")
+	if err := l.Check(); err == nil {
+		t.Error("Check() of a moved log succeeded")
+	}
+}
+
+func TestOpenVerifies(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		modify func(data string) string
+	}{
+		{name: "modified", modify: func(data string) string { return strings.Replace(data, `"amount":"1000"`, `"amount":"9000"`, 1) }},
+		{name: "truncated", modify: func(data string) string { return data[:len(data)-10] }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit.jsonl")
+			writeRecords(t, path, start, start.Add(time.Hour))
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			os.WriteFile(path, []byte(tt.modify(string(data))), 0600)
+			if l, err := Open(path, "salt"); err == nil {
+				l.Close()
+				t.Error("Open() of a broken log succeeded")
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package chain
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	transferDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "faucet_transfer_duration_seconds",
+		Help:    "Time to look up the nonce and gas price and send a transfer.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"chain_id", "result"})
+	rpcDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "faucet_rpc_duration_seconds",
+		Help:    "Duration of RPC calls by provider host and method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"provider", "method", "result"})
+)
+
+func resultLabel(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+// providerLabel returns the host of the provider, leaving out any API key in
+// its path or query.
+func providerLabel(provider string) string {
+	if u, err := url.Parse(provider); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return "unknown"
+}
+
+func observeSince(observer prometheus.ObserverVec, start time.Time, labels ...string) {
+	observer.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+}
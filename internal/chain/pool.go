@@ -0,0 +1,328 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	nodeTimeout    = 5 * time.Second
+	healthInterval = 15 * time.Second
+)
+
+type nodeClient interface {
+	txClient
+	chainIDReader
+	Close()
+}
+
+type node struct {
+	url     string
+	label   string
+	client  nodeClient
+	healthy int32
+}
+
+func (n *node) isHealthy() bool {
+	return atomic.LoadInt32(&n.healthy) == 1
+}
+
+// setHealthy records the health of the node and logs when it changes.
+func (n *node) setHealthy(healthy bool, err error) {
+	var value int32
+	if healthy {
+		value = 1
+	}
+	if atomic.SwapInt32(&n.healthy, value) == value {
+		return
+	}
+	if healthy {
+		log.WithField("provider", n.url).Info("Provider is healthy again")
+	} else {
+		log.WithError(err).WithField("provider", n.url).Warn("Provider is unhealthy")
+	}
+}
+
+// clientPool spreads reads over the healthy nodes, failing over to the next
+// one on connection errors and timeouts, and broadcasts transactions to all
+// nodes.
+type clientPool struct {
+	nodes   []*node
+	chainID *big.Int
+	next    uint32
+	quit    chan struct{}
+	once    sync.Once
+}
+
+func dialPool(ctx context.Context, urls []string) (*clientPool, error) {
+	p := &clientPool{quit: make(chan struct{})}
+	for _, url := range urls {
+		client, err := ethclient.DialContext(ctx, url)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.nodes = append(p.nodes, &node{url: url, label: providerLabel(url), client: client, healthy: 1})
+	}
+	return p, nil
+}
+
+func (p *clientPool) Close() {
+	p.once.Do(func() {
+		close(p.quit)
+		for _, n := range p.nodes {
+			n.client.Close()
+		}
+	})
+}
+
+// verify checks the nodes like checkNodes and makes the chain they are on the
+// chain of the pool.
+func (p *clientPool) verify(ctx context.Context, expected *big.Int) (*big.Int, error) {
+	chainID, err := p.checkNodes(ctx, expected)
+	if err != nil {
+		return nil, err
+	}
+	p.chainID = chainID
+	return chainID, nil
+}
+
+// checkNodes checks that every reachable node is on the expected chain, which
+// is taken from the first of them if it is nil. Unreachable nodes are marked
+// unhealthy, but at least one node must be reachable.
+func (p *clientPool) checkNodes(ctx context.Context, expected *big.Int) (*big.Int, error) {
+	var reachable int
+	var lastErr error
+	for _, n := range p.nodes {
+		nodeCtx, cancel := context.WithTimeout(ctx, nodeTimeout)
+		chainID, err := verifyChainID(nodeCtx, n.client, expected)
+		cancel()
+		if errors.Is(err, ErrChainIDMismatch) {
+			return nil, fmt.Errorf("%s: %w", n.url, err)
+		} else if err != nil {
+			n.setHealthy(false, err)
+			lastErr = err
+			continue
+		}
+		expected = chainID
+		reachable++
+	}
+	if reachable == 0 {
+		return nil, lastErr
+	}
+	return expected, nil
+}
+
+// watchHealth checks periodically that every node is reachable and on the
+// chain of the pool until the pool is closed.
+func (p *clientPool) watchHealth() {
+	ticker := time.NewTicker(healthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.checkHealth()
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+func (p *clientPool) checkHealth() {
+	var wg sync.WaitGroup
+	for _, n := range p.nodes {
+		wg.Add(1)
+		go func(n *node) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), nodeTimeout)
+			defer cancel()
+			_, err := verifyChainID(ctx, n.client, p.chainID)
+			n.setHealthy(err == nil, err)
+		}(n)
+	}
+	wg.Wait()
+}
+
+// candidates returns the healthy nodes in round-robin order, followed by the
+// unhealthy ones as a last resort.
+func (p *clientPool) candidates() []*node {
+	start := int(atomic.AddUint32(&p.next, 1))
+	healthy := make([]*node, 0, len(p.nodes))
+	var unhealthy []*node
+	for i := range p.nodes {
+		n := p.nodes[(start+i)%len(p.nodes)]
+		if n.isHealthy() {
+			healthy = append(healthy, n)
+		} else {
+			unhealthy = append(unhealthy, n)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+// isNodeError reports whether the error is caused by the node rather than by
+// the request, so that it is worth trying another node.
+func isNodeError(err error) bool {
+	var rpcErr rpc.Error
+	return err != nil && !errors.Is(err, ethereum.NotFound) && !errors.As(err, &rpcErr)
+}
+
+// read calls fn on one node after the other until a node answers.
+func (p *clientPool) read(ctx context.Context, method string, fn func(ctx context.Context, client nodeClient) error) error {
+	var err error
+	for _, n := range p.candidates() {
+		nodeCtx, cancel := context.WithTimeout(ctx, nodeTimeout)
+		nodeCtx, span := startRPC(nodeCtx, n.label, method)
+		start := time.Now()
+		err = fn(nodeCtx, n.client)
+		observeSince(rpcDuration, start, n.label, method, resultLabel(err))
+		endSpan(span, err)
+		cancel()
+		if ctx.Err() != nil || !isNodeError(err) {
+			return err
+		}
+		n.setHealthy(false, err)
+	}
+	return err
+}
+
+func (p *clientPool) HeaderByNumber(ctx context.Context, number *big.Int) (header *types.Header, err error) {
+	err = p.read(ctx, "eth_getBlockByNumber", func(ctx context.Context, client nodeClient) error {
+		header, err = client.HeaderByNumber(ctx, number)
+		return err
+	})
+	return header, err
+}
+
+func (p *clientPool) PendingCodeAt(ctx context.Context, account common.Address) (code []byte, err error) {
+	err = p.read(ctx, "eth_getCode", func(ctx context.Context, client nodeClient) error {
+		code, err = client.PendingCodeAt(ctx, account)
+		return err
+	})
+	return code, err
+}
+
+func (p *clientPool) PendingNonceAt(ctx context.Context, account common.Address) (nonce uint64, err error) {
+	err = p.read(ctx, "eth_getTransactionCount", func(ctx context.Context, client nodeClient) error {
+		nonce, err = client.PendingNonceAt(ctx, account)
+		return err
+	})
+	return nonce, err
+}
+
+func (p *clientPool) SuggestGasPrice(ctx context.Context) (price *big.Int, err error) {
+	err = p.read(ctx, "eth_gasPrice", func(ctx context.Context, client nodeClient) error {
+		price, err = client.SuggestGasPrice(ctx)
+		return err
+	})
+	return price, err
+}
+
+func (p *clientPool) SuggestGasTipCap(ctx context.Context) (tip *big.Int, err error) {
+	err = p.read(ctx, "eth_maxPriorityFeePerGas", func(ctx context.Context, client nodeClient) error {
+		tip, err = client.SuggestGasTipCap(ctx)
+		return err
+	})
+	return tip, err
+}
+
+func (p *clientPool) EstimateGas(ctx context.Context, call ethereum.CallMsg) (gas uint64, err error) {
+	err = p.read(ctx, "eth_estimateGas", func(ctx context.Context, client nodeClient) error {
+		gas, err = client.EstimateGas(ctx, call)
+		return err
+	})
+	return gas, err
+}
+
+func (p *clientPool) TransactionReceipt(ctx context.Context, txHash common.Hash) (receipt *types.Receipt, err error) {
+	err = p.read(ctx, "eth_getTransactionReceipt", func(ctx context.Context, client nodeClient) error {
+		receipt, err = client.TransactionReceipt(ctx, txHash)
+		return err
+	})
+	return receipt, err
+}
+
+func (p *clientPool) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) (code []byte, err error) {
+	err = p.read(ctx, "eth_getCode", func(ctx context.Context, client nodeClient) error {
+		code, err = client.CodeAt(ctx, account, blockNumber)
+		return err
+	})
+	return code, err
+}
+
+func (p *clientPool) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (balance *big.Int, err error) {
+	err = p.read(ctx, "eth_getBalance", func(ctx context.Context, client nodeClient) error {
+		balance, err = client.BalanceAt(ctx, account, blockNumber)
+		return err
+	})
+	return balance, err
+}
+
+func (p *clientPool) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (nonce uint64, err error) {
+	err = p.read(ctx, "eth_getTransactionCount", func(ctx context.Context, client nodeClient) error {
+		nonce, err = client.NonceAt(ctx, account, blockNumber)
+		return err
+	})
+	return nonce, err
+}
+
+func (p *clientPool) ChainID(ctx context.Context) (chainID *big.Int, err error) {
+	err = p.read(ctx, "eth_chainId", func(ctx context.Context, client nodeClient) error {
+		chainID, err = client.ChainID(ctx)
+		return err
+	})
+	return chainID, err
+}
+
+// SendTransaction broadcasts the transaction to all nodes. It succeeds if any
+// node accepts it, and otherwise prefers the error of a node that rejected it
+// over connection errors.
+func (p *clientPool) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	errs := make([]error, len(p.nodes))
+	var wg sync.WaitGroup
+	for i, n := range p.nodes {
+		wg.Add(1)
+		go func(i int, n *node) {
+			defer wg.Done()
+			nodeCtx, cancel := context.WithTimeout(ctx, nodeTimeout)
+			defer cancel()
+			nodeCtx, span := startRPC(nodeCtx, n.label, "eth_sendRawTransaction")
+			start := time.Now()
+			errs[i] = n.client.SendTransaction(nodeCtx, tx)
+			observeSince(rpcDuration, start, n.label, "eth_sendRawTransaction", resultLabel(errs[i]))
+			endSpan(span, errs[i])
+			if ctx.Err() == nil && isNodeError(errs[i]) {
+				n.setHealthy(false, errs[i])
+			}
+		}(i, n)
+	}
+	wg.Wait()
+
+	var nodeErr, rejected error
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			return nil
+		case isNodeError(err):
+			nodeErr = err
+		case rejected == nil:
+			rejected = err
+		}
+	}
+	if rejected != nil {
+		return rejected
+	}
+	return nodeErr
+}
@@ -0,0 +1,182 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type rejectedError struct{}
+
+func (rejectedError) Error() string  { return "insufficient funds for gas * price + value" }
+func (rejectedError) ErrorCode() int { return -32000 }
+
+type fakeNode struct {
+	nodeClient
+	chainID int64
+	err     error
+	calls   int
+}
+
+func (f *fakeNode) ChainID(context.Context) (*big.Int, error) {
+	f.calls++
+	return big.NewInt(f.chainID), f.err
+}
+
+func (f *fakeNode) PendingNonceAt(context.Context, common.Address) (uint64, error) {
+	f.calls++
+	return 7, f.err
+}
+
+func (f *fakeNode) SendTransaction(context.Context, *types.Transaction) error {
+	f.calls++
+	return f.err
+}
+
+func (f *fakeNode) Close() {}
+
+func newFakePool(nodes ...*fakeNode) *clientPool {
+	p := &clientPool{quit: make(chan struct{}), chainID: big.NewInt(5)}
+	for _, n := range nodes {
+		p.nodes = append(p.nodes, &node{url: "fake", client: n, healthy: 1})
+	}
+	return p
+}
+
+func TestClientPoolFailover(t *testing.T) {
+	down := &fakeNode{chainID: 5, err: errors.New("connection refused")}
+	up := &fakeNode{chainID: 5}
+	p := newFakePool(down, up)
+
+	for i := 0; i < 3; i++ {
+		nonce, err := p.PendingNonceAt(context.Background(), common.Address{})
+		if err != nil || nonce != 7 {
+			t.Fatalf("PendingNonceAt() = %d, %v, want failover to the healthy node", nonce, err)
+		}
+	}
+	if down.calls != 1 || p.nodes[0].isHealthy() {
+		t.Errorf("failed node was called %d times, healthy = %v, want it skipped after the first error", down.calls, p.nodes[0].isHealthy())
+	}
+
+	down.err = nil
+	p.checkHealth()
+	if !p.nodes[0].isHealthy() {
+		t.Error("recovered node is still unhealthy after the health check")
+	}
+
+	// A node that was re-pointed to another chain is taken out of rotation
+	down.chainID = 1
+	p.checkHealth()
+	if p.nodes[0].isHealthy() {
+		t.Error("node on another chain is healthy")
+	}
+}
+
+func TestClientPoolBroadcast(t *testing.T) {
+	tests := []struct {
+		name    string
+		errs    []error
+		wantErr error
+	}{
+		{name: "all accept", errs: []error{nil, nil}},
+		{name: "one accepts", errs: []error{errors.New("connection refused"), nil}},
+		{name: "rejected", errs: []error{errors.New("connection refused"), rejectedError{}}, wantErr: rejectedError{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := make([]*fakeNode, len(tt.errs))
+			for i, err := range tt.errs {
+				nodes[i] = &fakeNode{err: err}
+			}
+			p := newFakePool(nodes...)
+			err := p.SendTransaction(context.Background(), types.NewTx(&types.LegacyTx{}))
+			if err != tt.wantErr {
+				t.Errorf("SendTransaction() error = %v, want %v", err, tt.wantErr)
+			}
+			for i, n := range nodes {
+				if n.calls != 1 {
+					t.Errorf("node %d received %d broadcasts, want 1", i, n.calls)
+				}
+			}
+		})
+	}
+}
+
+func TestClientPoolVerify(t *testing.T) {
+	tests := []struct {
+		name     string
+		nodes    []*fakeNode
+		expected *big.Int
+		want     int64
+		wantErr  bool
+	}{
+		{name: "agreeing nodes", nodes: []*fakeNode{{chainID: 5}, {chainID: 5}}, want: 5},
+		{name: "one unreachable", nodes: []*fakeNode{{err: errors.New("connection refused")}, {chainID: 5}}, expected: big.NewInt(5), want: 5},
+		{name: "disagreeing nodes", nodes: []*fakeNode{{chainID: 5}, {chainID: 1}}, wantErr: true},
+		{name: "all unreachable", nodes: []*fakeNode{{err: errors.New("connection refused")}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newFakePool(tt.nodes...)
+			chainID, err := p.verify(context.Background(), tt.expected)
+			if (err != nil) != tt.wantErr || (err == nil && chainID.Int64() != tt.want) {
+				t.Errorf("verify() = %v, %v, want %d", chainID, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientPoolSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	p := newFakePool(&fakeNode{err: errors.New("connection refused")}, &fakeNode{})
+	// Start the round-robin at the failing node
+	p.next = uint32(len(p.nodes) - 1)
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "claim")
+	if _, err := p.PendingNonceAt(ctx, common.Address{}); err != nil {
+		t.Fatalf("PendingNonceAt() error = %v", err)
+	}
+	parent.End()
+
+	// One span per node that was tried, the failed one first
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("recorded %d spans, want 3", len(spans))
+	}
+	for i, want := range []codes.Code{codes.Error, codes.Unset} {
+		span := spans[i]
+		if span.Name() != "eth_getTransactionCount" || span.Parent().SpanID() != parent.SpanContext().SpanID() || span.Status().Code != want {
+			t.Errorf("span %d = %s with status %v, want an RPC span below the claim with status %v", i, span.Name(), span.Status().Code, want)
+		}
+	}
+}
+
+func TestTxBuildVerifiesEveryNode(t *testing.T) {
+	fallback := &fakeNode{chainID: 5}
+	p := newFakePool(&fakeNode{chainID: 5}, fallback)
+	b := &TxBuild{client: p, signer: types.NewEIP155Signer(big.NewInt(5))}
+	if err := b.VerifyChainID(context.Background()); err != nil {
+		t.Fatalf("VerifyChainID() error = %v", err)
+	}
+
+	fallback.chainID = 1
+	if err := b.VerifyChainID(context.Background()); !errors.Is(err, ErrChainIDMismatch) {
+		t.Errorf("VerifyChainID() error = %v with a fallback on another chain, want %v", err, ErrChainIDMismatch)
+	}
+
+	b.Close()
+	select {
+	case <-p.quit:
+	default:
+		t.Error("Close() did not stop the health checks")
+	}
+}
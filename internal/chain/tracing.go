@@ -0,0 +1,36 @@
+package chain
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer is a no-op until a tracer provider is installed.
+var tracer = otel.Tracer("github.com/chainflag/eth-faucet/internal/chain")
+
+// startRPC starts the span of an RPC call to a provider.
+func startRPC(ctx context.Context, provider, method string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, method, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.RPCSystemKey.String("jsonrpc"),
+		semconv.RPCMethodKey.String(method),
+		semconv.NetPeerNameKey.String(provider),
+	))
+}
+
+// endSpan records the error of a finished call before ending its span.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func chainIDAttribute(b *TxBuild) attribute.KeyValue {
+	return attribute.String("faucet.chain_id", b.signer.ChainID().String())
+}
@@ -3,39 +3,112 @@ package chain
 import (
 	"context"
 	"crypto/ecdsa"
+	"errors"
+	"fmt"
 	"math/big"
+	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const dialTimeout = 10 * time.Second
+
+// ErrChainIDMismatch is returned when the provider reports another chain than
+// the one transactions are signed for.
+var ErrChainIDMismatch = errors.New("chain ID mismatch")
+
 type TxBuilder interface {
 	Sender() common.Address
 	Transfer(ctx context.Context, to string, value *big.Int) (common.Hash, error)
 }
 
+// BalanceReader is implemented by builders that can look up the balance of the sender.
+type BalanceReader interface {
+	Balance(ctx context.Context) (*big.Int, error)
+}
+
+// TxWatcher is implemented by builders that can look up the inclusion of sent transactions.
+type TxWatcher interface {
+	TransactionStatus(ctx context.Context, txHash common.Hash) (*TxStatus, error)
+}
+
+// ChainVerifier is implemented by builders that can check that their provider
+// is still on the chain they sign transactions for.
+type ChainVerifier interface {
+	VerifyChainID(ctx context.Context) error
+}
+
+// Closer is implemented by builders that hold connections to their providers,
+// which are released by Close.
+type Closer interface {
+	Close()
+}
+
+// NonceGapReader is implemented by builders that can tell how many of their
+// transactions are sent but not yet mined.
+type NonceGapReader interface {
+	PendingNonceGap(ctx context.Context) (uint64, error)
+}
+
+// SignerChecker is implemented by builders that can check their signer without
+// sending a transaction.
+type SignerChecker interface {
+	CheckSigner() error
+}
+
+type TxStatus struct {
+	BlockNumber   uint64
+	Confirmations uint64
+	Successful    bool
+}
+
+// BatchTxBuilder is implemented by builders that can send several transfers
+// with a single nonce and gas price lookup.
+type BatchTxBuilder interface {
+	BatchTransfer(ctx context.Context, to []string, values []*big.Int) ([]common.Hash, error)
+}
+
+type txClient interface {
+	bind.ContractTransactor
+	bind.DeployBackend
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+}
+
+type chainIDReader interface {
+	ChainID(ctx context.Context) (*big.Int, error)
+}
+
 type TxBuild struct {
-	client      bind.ContractTransactor
+	client      txClient
 	privateKey  *ecdsa.PrivateKey
 	signer      types.Signer
 	fromAddress common.Address
 }
 
+// NewTxBuilder connects to a comma separated list of providers and checks that
+// they report the given chain ID. A nil chain ID accepts the one reported by
+// the providers, which must agree.
 func NewTxBuilder(provider string, privateKey *ecdsa.PrivateKey, chainID *big.Int) (TxBuilder, error) {
-	client, err := ethclient.Dial(provider)
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+	client, err := dialPool(ctx, SplitList(provider))
 	if err != nil {
 		return nil, err
 	}
 
-	if chainID == nil {
-		chainID, err = client.ChainID(context.Background())
-		if err != nil {
-			return nil, err
-		}
+	chainID, err = client.verify(ctx, chainID)
+	if err != nil {
+		client.Close()
+		return nil, err
 	}
+	go client.watchHealth()
 
 	return &TxBuild{
 		client:      client,
@@ -45,22 +118,164 @@ func NewTxBuilder(provider string, privateKey *ecdsa.PrivateKey, chainID *big.In
 	}, nil
 }
 
+// SplitList returns the non-empty items of a comma separated list, such as the
+// endpoints of a provider list.
+func SplitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func verifyChainID(ctx context.Context, client chainIDReader, expected *big.Int) (*big.Int, error) {
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if expected != nil && expected.Cmp(chainID) != 0 {
+		return nil, fmt.Errorf("%w: provider is on chain %s, expected chain %s", ErrChainIDMismatch, chainID, expected)
+	}
+	return chainID, nil
+}
+
 func (b *TxBuild) Sender() common.Address {
 	return b.fromAddress
 }
 
-func (b *TxBuild) Transfer(ctx context.Context, to string, value *big.Int) (common.Hash, error) {
+// VerifyChainID checks that every provider still reports the chain ID the
+// transactions are signed for, so that a fallback provider on another chain is
+// noticed before reads fail over to it.
+func (b *TxBuild) VerifyChainID(ctx context.Context) error {
+	if pool, ok := b.client.(*clientPool); ok {
+		_, err := pool.checkNodes(ctx, b.signer.ChainID())
+		return err
+	}
+	client, ok := b.client.(chainIDReader)
+	if !ok {
+		return nil
+	}
+	_, err := verifyChainID(ctx, client, b.signer.ChainID())
+	return err
+}
+
+// Close disconnects from the providers and stops their health checks.
+func (b *TxBuild) Close() {
+	if pool, ok := b.client.(*clientPool); ok {
+		pool.Close()
+	}
+}
+
+// CheckSigner signs a transaction that is never sent and checks that it
+// recovers to the sender.
+func (b *TxBuild) CheckSigner() error {
+	tx, err := types.SignTx(types.NewTransaction(0, b.fromAddress, new(big.Int), 21000, new(big.Int), nil), b.signer, b.privateKey)
+	if err != nil {
+		return err
+	}
+	sender, err := types.Sender(b.signer, tx)
+	if err != nil {
+		return err
+	}
+	if sender != b.fromAddress {
+		return fmt.Errorf("signer recovers to %s instead of %s", sender, b.fromAddress)
+	}
+	return nil
+}
+
+func (b *TxBuild) Balance(ctx context.Context) (*big.Int, error) {
+	return b.client.BalanceAt(ctx, b.Sender(), nil)
+}
+
+func (b *TxBuild) PendingNonceGap(ctx context.Context) (uint64, error) {
+	pending, err := b.client.PendingNonceAt(ctx, b.Sender())
+	if err != nil {
+		return 0, err
+	}
+	mined, err := b.client.NonceAt(ctx, b.Sender(), nil)
+	if err != nil || pending < mined {
+		return 0, err
+	}
+	return pending - mined, nil
+}
+
+func (b *TxBuild) Transfer(ctx context.Context, to string, value *big.Int) (txHash common.Hash, err error) {
+	ctx, span := tracer.Start(ctx, "TxBuild.Transfer", trace.WithAttributes(chainIDAttribute(b)))
+	defer func(start time.Time) {
+		observeSince(transferDuration, start, b.signer.ChainID().String(), resultLabel(err))
+		endSpan(span, err)
+	}(time.Now())
+
 	nonce, err := b.client.PendingNonceAt(ctx, b.Sender())
 	if err != nil {
 		return common.Hash{}, err
 	}
 
-	gasLimit := uint64(21000)
 	gasPrice, err := b.client.SuggestGasPrice(ctx)
 	if err != nil {
 		return common.Hash{}, err
 	}
 
+	return b.send(ctx, nonce, gasPrice, to, value)
+}
+
+// BatchTransfer sends the transfers with consecutive nonces. On failure it
+// returns the hashes of the transfers that were sent before the error.
+func (b *TxBuild) BatchTransfer(ctx context.Context, to []string, values []*big.Int) (hashes []common.Hash, err error) {
+	ctx, span := tracer.Start(ctx, "TxBuild.BatchTransfer", trace.WithAttributes(chainIDAttribute(b), attribute.Int("faucet.batch_size", len(to))))
+	defer func(start time.Time) {
+		observeSince(transferDuration, start, b.signer.ChainID().String(), resultLabel(err))
+		endSpan(span, err)
+	}(time.Now())
+
+	nonce, err := b.client.PendingNonceAt(ctx, b.Sender())
+	if err != nil {
+		return nil, err
+	}
+
+	gasPrice, err := b.client.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes = make([]common.Hash, 0, len(to))
+	for i := range to {
+		txHash, err := b.send(ctx, nonce+uint64(i), gasPrice, to[i], values[i])
+		if err != nil {
+			return hashes, err
+		}
+		hashes = append(hashes, txHash)
+	}
+	return hashes, nil
+}
+
+// TransactionStatus returns ethereum.NotFound while the transaction is pending.
+func (b *TxBuild) TransactionStatus(ctx context.Context, txHash common.Hash) (*TxStatus, error) {
+	receipt, err := b.client.TransactionReceipt(ctx, txHash)
+	if err != nil {
+		return nil, err
+	}
+	head, err := b.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	blockNumber := receipt.BlockNumber.Uint64()
+	var confirmations uint64
+	if head.Number.Uint64() >= blockNumber {
+		confirmations = head.Number.Uint64() - blockNumber + 1
+	}
+	return &TxStatus{
+		BlockNumber:   blockNumber,
+		Confirmations: confirmations,
+		Successful:    receipt.Status == types.ReceiptStatusSuccessful,
+	}, nil
+}
+
+func (b *TxBuild) send(ctx context.Context, nonce uint64, gasPrice *big.Int, to string, value *big.Int) (common.Hash, error) {
+	gasLimit := uint64(21000)
 	toAddress := common.HexToAddress(to)
 	unsignedTx := types.NewTx(&types.LegacyTx{
 		Nonce:    nonce,
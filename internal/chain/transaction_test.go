@@ -2,6 +2,7 @@ package chain
 
 import (
 	"context"
+	"errors"
 	"math/big"
 	"reflect"
 	"testing"
@@ -59,4 +60,118 @@ func TestTxBuilder(t *testing.T) {
 	if bal.Cmp(value) != 0 {
 		t.Errorf("expected balance for to address not received. expected: %v actual: %v", value, bal)
 	}
+
+	simClient.Commit()
+	status, err := txBuilder.TransactionStatus(bgCtx, txHash)
+	if err != nil {
+		t.Fatalf("could not get transaction status: %v", err)
+	}
+	want := &TxStatus{BlockNumber: 1, Confirmations: 2, Successful: true}
+	if !reflect.DeepEqual(status, want) {
+		t.Errorf("unexpected transaction status. expected: %+v actual: %+v", want, status)
+	}
+}
+
+func TestBatchTransfer(t *testing.T) {
+	privateKey, _ := crypto.HexToECDSA("976f9f7772781ff6d1c93941129d417c49a209c674056a3cf5e27e225ee55fa8")
+	fromAddress := crypto.PubkeyToAddress(privateKey.PublicKey)
+	simClient := backends.NewSimulatedBackend(
+		core.GenesisAlloc{
+			fromAddress: {Balance: big.NewInt(10000000000000000)},
+		}, 10000000,
+	)
+	defer simClient.Close()
+	var s *backends.SimulatedBackend
+	patches := gomonkey.ApplyMethod(reflect.TypeOf(s), "SuggestGasPrice", func(_ *backends.SimulatedBackend, _ context.Context) (*big.Int, error) {
+		return big.NewInt(875000000), nil
+	})
+	defer patches.Reset()
+
+	txBuilder := &TxBuild{
+		client:      simClient,
+		privateKey:  privateKey,
+		signer:      types.NewEIP155Signer(big.NewInt(1337)),
+		fromAddress: fromAddress,
+	}
+	bgCtx := context.Background()
+	recipients := []string{
+		"0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B",
+		"0x4B0897b0513fdC7C541B6d9D7E929C4e5364D2dB",
+	}
+	values := []*big.Int{big.NewInt(1000), big.NewInt(2000)}
+	txHashes, err := txBuilder.BatchTransfer(bgCtx, recipients, values)
+	if err != nil {
+		t.Fatalf("could not add txs to pending block: %v", err)
+	}
+	simClient.Commit()
+
+	block, err := simClient.BlockByNumber(bgCtx, big.NewInt(1))
+	if err != nil {
+		t.Fatalf("could not get block at height 1: %v", err)
+	}
+	if len(block.Transactions()) != len(recipients) {
+		t.Fatalf("expected %d transactions in block, got %d", len(recipients), len(block.Transactions()))
+	}
+	for i, tx := range block.Transactions() {
+		if txHashes[i] != tx.Hash() {
+			t.Errorf("did not commit sent transaction. expected hash %v got hash %v", tx.Hash(), txHashes[i])
+		}
+		bal, err := simClient.BalanceAt(bgCtx, common.HexToAddress(recipients[i]), nil)
+		if err != nil {
+			t.Error(err)
+		}
+		if bal.Cmp(values[i]) != 0 {
+			t.Errorf("expected balance for to address not received. expected: %v actual: %v", values[i], bal)
+		}
+	}
+}
+
+type chainIDFunc func(ctx context.Context) (*big.Int, error)
+
+func (f chainIDFunc) ChainID(ctx context.Context) (*big.Int, error) {
+	return f(ctx)
+}
+
+func TestVerifyChainID(t *testing.T) {
+	tests := []struct {
+		name     string
+		reported int64
+		expected *big.Int
+		wantErr  bool
+	}{
+		{name: "any chain", reported: 5},
+		{name: "same chain", reported: 5, expected: big.NewInt(5)},
+		{name: "other chain", reported: 1, expected: big.NewInt(5), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := chainIDFunc(func(context.Context) (*big.Int, error) { return big.NewInt(tt.reported), nil })
+			chainID, err := verifyChainID(context.Background(), client, tt.expected)
+			if tt.wantErr {
+				if !errors.Is(err, ErrChainIDMismatch) {
+					t.Errorf("verifyChainID() error = %v, want %v", err, ErrChainIDMismatch)
+				}
+				return
+			}
+			if err != nil || chainID.Int64() != tt.reported {
+				t.Errorf("verifyChainID() = %v, %v, want %d", chainID, err, tt.reported)
+			}
+		})
+	}
+}
+
+func TestCheckSigner(t *testing.T) {
+	privateKey, _ := crypto.HexToECDSA("976f9f7772781ff6d1c93941129d417c49a209c674056a3cf5e27e225ee55fa8")
+	txBuilder := &TxBuild{
+		privateKey:  privateKey,
+		signer:      types.NewEIP155Signer(big.NewInt(1337)),
+		fromAddress: crypto.PubkeyToAddress(privateKey.PublicKey),
+	}
+	if err := txBuilder.CheckSigner(); err != nil {
+		t.Errorf("CheckSigner() error = %v", err)
+	}
+	txBuilder.fromAddress = common.HexToAddress("0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B")
+	if err := txBuilder.CheckSigner(); err == nil {
+		t.Error("CheckSigner() of another sender succeeded")
+	}
 }
@@ -1,7 +1,9 @@
 package chain
 
 import (
+	"errors"
 	"math/big"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
 )
@@ -11,6 +13,32 @@ func EtherToWei(amount int64) *big.Int {
 	return new(big.Int).Mul(big.NewInt(amount), ether)
 }
 
+// WeiToEther converts an amount of Wei to Ether, which may lose precision.
+func WeiToEther(wei *big.Int) float64 {
+	ether, _ := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e18)).Float64()
+	return ether
+}
+
+// ParseEther converts a decimal amount of Ether such as "0.05" to Wei.
+func ParseEther(amount string) (*big.Int, error) {
+	whole, fraction := amount, ""
+	if i := strings.IndexByte(amount, '.'); i >= 0 {
+		whole, fraction = amount[:i], amount[i+1:]
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	if len(fraction) > 18 || strings.ContainsAny(whole+fraction, "+-") {
+		return nil, errors.New("invalid ether amount")
+	}
+
+	value, ok := new(big.Int).SetString(whole+fraction+strings.Repeat("0", 18-len(fraction)), 10)
+	if !ok {
+		return nil, errors.New("invalid ether amount")
+	}
+	return value, nil
+}
+
 func Has0xPrefix(str string) bool {
 	return len(str) >= 2 && str[0] == '0' && (str[1] == 'x' || str[1] == 'X')
 }
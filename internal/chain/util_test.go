@@ -48,3 +48,50 @@ func TestEtherToWei(t *testing.T) {
 		})
 	}
 }
+
+func TestWeiToEther(t *testing.T) {
+	tests := []struct {
+		name string
+		wei  *big.Int
+		want float64
+	}{
+		{name: "1ether", wei: EtherToWei(1), want: 1},
+		{name: "half ether", wei: big.NewInt(5e17), want: 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WeiToEther(tt.wei); got != tt.want {
+				t.Errorf("WeiToEther() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEther(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  string
+		want    *big.Int
+		wantErr bool
+	}{
+		{name: "whole", amount: "2", want: EtherToWei(2)},
+		{name: "fraction", amount: "0.1", want: big.NewInt(100000000000000000)},
+		{name: "leading dot", amount: ".5", want: big.NewInt(500000000000000000)},
+		{name: "wei", amount: "0.000000000000000001", want: big.NewInt(1)},
+		{name: "too precise", amount: "0.0000000000000000001", wantErr: true},
+		{name: "negative", amount: "-1", wantErr: true},
+		{name: "invalid", amount: "one", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseEther(tt.amount)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseEther() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got.Cmp(tt.want) != 0 {
+				t.Errorf("ParseEther() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DailyBuckets and HourlyBuckets are the lengths of the histograms
+	DailyBuckets  = 30
+	HourlyBuckets = 24
+)
+
+// Entry is a claim that was sent.
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Network string    `json:"network,omitempty"`
+	Address string    `json:"address"`
+	Amount  string    `json:"amount"`
+}
+
+// Bucket sums the claims sent in the day or hour starting at Time.
+type Bucket struct {
+	Time   time.Time
+	Claims int
+	Amount *big.Int
+}
+
+// Stats sums up the claim history of a network.
+type Stats struct {
+	Claims    int
+	Amount    *big.Int
+	Addresses int
+	Daily     []Bucket
+	Hourly    []Bucket
+}
+
+type aggregate struct {
+	claims    int
+	amount    *big.Int
+	addresses map[string]bool
+	days      map[int64]*Bucket
+	hours     map[int64]*Bucket
+}
+
+func newAggregate() *aggregate {
+	return &aggregate{
+		amount:    new(big.Int),
+		addresses: make(map[string]bool),
+		days:      make(map[int64]*Bucket),
+		hours:     make(map[int64]*Bucket),
+	}
+}
+
+func (a *aggregate) add(e Entry, amount *big.Int) {
+	a.claims++
+	a.amount.Add(a.amount, amount)
+	a.addresses[strings.ToLower(e.Address)] = true
+	addBucket(a.days, e.Time.Truncate(24*time.Hour), amount)
+	hour := e.Time.Truncate(time.Hour)
+	addBucket(a.hours, hour, amount)
+	// Hours older than the histogram are never shown again
+	for start := range a.hours {
+		if start <= hour.Add(-HourlyBuckets*time.Hour).Unix() {
+			delete(a.hours, start)
+		}
+	}
+}
+
+func addBucket(buckets map[int64]*Bucket, start time.Time, amount *big.Int) {
+	bucket, ok := buckets[start.Unix()]
+	if !ok {
+		bucket = &Bucket{Time: start, Amount: new(big.Int)}
+		buckets[start.Unix()] = bucket
+	}
+	bucket.Claims++
+	bucket.Amount.Add(bucket.Amount, amount)
+}
+
+// histogram returns count buckets of the given size up to the one of now,
+// filling in those without claims.
+func histogram(buckets map[int64]*Bucket, now time.Time, size time.Duration, count int) []Bucket {
+	last := now.Truncate(size)
+	histogram := make([]Bucket, count)
+	for i := range histogram {
+		start := last.Add(-time.Duration(count-1-i) * size)
+		histogram[i] = Bucket{Time: start, Amount: new(big.Int)}
+		if bucket, ok := buckets[start.Unix()]; ok {
+			histogram[i].Claims = bucket.Claims
+			histogram[i].Amount.Set(bucket.Amount)
+		}
+	}
+	return histogram
+}
+
+// Store appends sent claims to a JSONL file and keeps running totals of them
+// per network, which are rebuilt from the file when it is opened.
+type Store struct {
+	mutex    sync.Mutex
+	path     string
+	file     *os.File
+	networks map[string]*aggregate
+}
+
+// Open opens the history at path and sums up the claims in it.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, networks: make(map[string]*aggregate)}
+	if f, err := os.Open(path); err == nil {
+		err = s.load(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	s.file = file
+	return s, nil
+}
+
+func (s *Store) load(f *os.File) error {
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		amount, ok := new(big.Int).SetString(entry.Amount, 10)
+		if !ok {
+			return fmt.Errorf("line %d: invalid amount %q", line, entry.Amount)
+		}
+		s.aggregate(entry.Network).add(entry, amount)
+	}
+	return scanner.Err()
+}
+
+// aggregate returns the totals of a network. The caller must hold s.mutex
+// unless the store is still being opened.
+func (s *Store) aggregate(network string) *aggregate {
+	a, ok := s.networks[network]
+	if !ok {
+		a = newAggregate()
+		s.networks[network] = a
+	}
+	return a
+}
+
+// Add writes a sent claim to disk and adds it to the totals.
+func (s *Store) Add(e Entry) error {
+	amount, ok := new(big.Int).SetString(e.Amount, 10)
+	if !ok {
+		return fmt.Errorf("invalid amount %q", e.Amount)
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC()
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	s.aggregate(e.Network).add(e, amount)
+	return nil
+}
+
+// Stats returns the totals of a network and its histograms up to now.
+func (s *Store) Stats(network string, now time.Time) Stats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	a := s.aggregate(network)
+	now = now.UTC()
+	return Stats{
+		Claims:    a.claims,
+		Amount:    new(big.Int).Set(a.amount),
+		Addresses: len(a.addresses),
+		Daily:     histogram(a.days, now, 24*time.Hour, DailyBuckets),
+		Hourly:    histogram(a.hours, now, time.Hour, HourlyBuckets),
+	}
+}
+
+// Check reports whether the file is still in place. Claims added to a file
+// that was removed or moved away would be lost on restart.
+func (s *Store) Check() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+	open, err := s.file.Stat()
+	if err != nil {
+		return err
+	}
+	if !os.SameFile(info, open) {
+		return fmt.Errorf("%s was replaced", s.path)
+	}
+	return nil
+}
+
+func (s *Store) Close() error {
+	return s.file.Close()
+}
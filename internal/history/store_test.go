@@ -0,0 +1,59 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStoreStats(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "claims.jsonl")
+	now := time.Date(2024, 1, 31, 12, 30, 0, 0, time.UTC)
+	entries := []Entry{
+		{Time: now.Add(-40 * 24 * time.Hour), Address: "0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B", Amount: "1000"},
+		{Time: now.Add(-2 * time.Hour), Address: "0xab5801a7d398351b8be11c439e05c5b3259aec9b", Amount: "1000"},
+		{Time: now.Add(-10 * time.Minute), Address: "0x7ef5a6135f1fd6a02593eedc869c6d41d934aef8", Amount: "500"},
+		{Time: now, Network: "sepolia", Address: "0x7ef5a6135f1fd6a02593eedc869c6d41d934aef8", Amount: "7"},
+	}
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	for _, entry := range entries[:2] {
+		if err := store.Add(entry); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	store.Close()
+
+	// The totals are rebuilt from the file
+	store, err = Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer store.Close()
+	for _, entry := range entries[2:] {
+		if err := store.Add(entry); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	stats := store.Stats("", now)
+	if stats.Claims != 3 || stats.Amount.String() != "2500" || stats.Addresses != 2 {
+		t.Errorf("Stats() = %d claims of %s to %d addresses, want 3 claims of 2500 to 2 addresses", stats.Claims, stats.Amount, stats.Addresses)
+	}
+	if len(stats.Daily) != DailyBuckets || len(stats.Hourly) != HourlyBuckets {
+		t.Fatalf("Stats() has %d daily and %d hourly buckets", len(stats.Daily), len(stats.Hourly))
+	}
+	today := stats.Daily[DailyBuckets-1]
+	if !today.Time.Equal(time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)) || today.Claims != 2 || today.Amount.String() != "1500" {
+		t.Errorf("daily bucket of today = %+v, want 2 claims of 1500", today)
+	}
+	if stats.Hourly[HourlyBuckets-1].Claims != 1 || stats.Hourly[HourlyBuckets-3].Claims != 1 || stats.Hourly[HourlyBuckets-2].Claims != 0 {
+		t.Errorf("hourly buckets = %+v, want claims 2 hours ago and this hour", stats.Hourly)
+	}
+
+	if other := store.Stats("sepolia", now); other.Claims != 1 || other.Amount.String() != "7" {
+		t.Errorf("Stats(sepolia) = %d claims of %s, want 1 claim of 7", other.Claims, other.Amount)
+	}
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type adminStatusResponse struct {
+	Paused     bool `json:"paused"`
+	LowBalance bool `json:"lowBalance"`
+	Payout     int  `json:"payout"`
+	Interval   int  `json:"minutes"`
+	Queued     int  `json:"queued"`
+}
+
+type adminConfigRequest struct {
+	Payout   *int `json:"payout"`
+	Interval *int `json:"minutes"`
+}
+
+// setupAdminRouter serves the admin API of the default network below /admin/
+// and of the other networks below /admin/{name}/.
+func (s *Server) setupAdminRouter() http.Handler {
+	router := s.adminRoutes()
+	for _, network := range s.networks {
+		mountNetwork(router, network.network, network.adminRoutes(), "/admin/")
+	}
+	return s.requireAdmin(router)
+}
+
+func (s *Server) adminRoutes() *http.ServeMux {
+	router := http.NewServeMux()
+	router.HandleFunc("/admin/status", s.handleAdminStatus)
+	router.HandleFunc("/admin/pause", s.handleAdminPause(true))
+	router.HandleFunc("/admin/resume", s.handleAdminPause(false))
+	router.HandleFunc("/admin/config", s.handleAdminConfig)
+	router.HandleFunc("/admin/queue", s.handleAdminQueue)
+	router.HandleFunc("/admin/queue/", s.handleAdminQueueItem)
+	router.HandleFunc("/admin/limits/", s.handleAdminLimit)
+	router.HandleFunc("/admin/claims", s.handleAdminClaims)
+	router.HandleFunc("/admin/claim/batch", s.handleBatchClaim(true))
+	return router
+}
+
+func (s *Server) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.isAdminToken(r.Header.Get("Authorization")) {
+			renderError(w, r, newAPIError(ErrUnauthorized, "Invalid admin token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isAdminToken reports whether the authorization header carries the admin
+// token. No header matches if the token is not configured.
+func (s *Server) isAdminToken(header string) bool {
+	token := strings.TrimPrefix(header, "Bearer ")
+	return s.cfg.Admin.Token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.Admin.Token)) == 1
+}
+
+func (s *Server) adminStatus() adminStatusResponse {
+	return adminStatusResponse{
+		Paused:     s.isPaused(),
+		LowBalance: s.isLowBalance(),
+		Payout:     s.cfg.Payout(),
+		Interval:   s.cfg.Interval(),
+		Queued:     s.queue.len(),
+	}
+}
+
+func (s *Server) handleAdminStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.NotFound(w, r)
+		return
+	}
+	renderJSON(w, s.adminStatus(), http.StatusOK)
+}
+
+func (s *Server) handleAdminPause(paused bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.NotFound(w, r)
+			return
+		}
+		s.SetPaused(paused)
+		renderJSON(w, s.adminStatus(), http.StatusOK)
+	}
+}
+
+func (s *Server) handleAdminConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "GET" {
+		renderJSON(w, s.adminStatus(), http.StatusOK)
+		return
+	} else if r.Method != "PUT" && r.Method != "PATCH" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var req adminConfigRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		renderReadError(w, r, err)
+		return
+	}
+	if (req.Payout != nil && *req.Payout <= 0) || (req.Interval != nil && *req.Interval < 0) {
+		renderError(w, r, newAPIError(ErrInvalidRequest, "Payout must be positive and minutes must not be negative"))
+		return
+	}
+	if req.Payout != nil {
+		s.cfg.SetPayout(*req.Payout)
+	}
+	if req.Interval != nil {
+		s.cfg.SetInterval(*req.Interval)
+		s.limiter.SetTTL(time.Duration(*req.Interval) * time.Minute)
+	}
+	logger(r.Context()).WithFields(log.Fields{
+		"payout":  s.cfg.Payout(),
+		"minutes": s.cfg.Interval(),
+	}).Warn("Faucet config changed by admin")
+	renderJSON(w, s.adminStatus(), http.StatusOK)
+}
+
+func (s *Server) handleAdminQueue(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		renderJSON(w, s.queue.list(), http.StatusOK)
+	case "DELETE":
+		items := s.queue.drain()
+		for _, item := range items {
+			s.tracker.report(item, statusCancelled, "", nil)
+		}
+		logger(r.Context()).WithField("count", len(items)).Warn("Queue cleared by admin")
+		renderJSON(w, items, http.StatusOK)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) handleAdminQueueItem(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "DELETE" {
+		http.NotFound(w, r)
+		return
+	}
+	item, ok := s.queue.remove(strings.TrimPrefix(r.URL.Path, "/admin/queue/"))
+	if !ok {
+		renderError(w, r, newAPIError(ErrNotFound, "Queue item not found"))
+		return
+	}
+	s.tracker.report(item, statusCancelled, "", nil)
+	logger(r.Context()).WithField("id", item.ID).Warn("Queue item removed by admin")
+	renderJSON(w, item, http.StatusOK)
+}
+
+func (s *Server) handleAdminLimit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "DELETE" {
+		http.NotFound(w, r)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/admin/limits/")
+	if !s.limiter.Reset(key) {
+		renderError(w, r, newAPIError(ErrNotFound, "Rate limit key not found"))
+		return
+	}
+	logger(r.Context()).WithField("key", key).Warn("Rate limit reset by admin")
+	renderJSON(w, claimResponse{Message: "Rate limit reset for " + key}, http.StatusOK)
+}
+
+func (s *Server) handleAdminClaims(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.NotFound(w, r)
+		return
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+	renderJSON(w, s.tracker.recent(limit), http.StatusOK)
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chainflag/eth-faucet/internal/chain"
+)
+
+func TestAdminRouter(t *testing.T) {
+	cfg := testConfig()
+	cfg.Admin.Token = "secret"
+	s := newTestServer(t, &mockTxBuilder{}, cfg)
+	s.queue.push(&queueItem{ID: "queued", Address: "0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B", Class: publicClassName, Amount: chain.EtherToWei(1)})
+	router := s.setupAdminRouter()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		token  string
+		status int
+	}{
+		{name: "unauthorized", method: "GET", path: "/admin/status", token: "wrong", status: http.StatusUnauthorized},
+		{name: "pause", method: "POST", path: "/admin/pause", token: "secret", status: http.StatusOK},
+		{name: "update config", method: "PUT", path: "/admin/config", body: `{"payout":3,"minutes":60}`, token: "secret", status: http.StatusOK},
+		{name: "invalid config", method: "PUT", path: "/admin/config", body: `{"payout":0}`, token: "secret", status: http.StatusBadRequest},
+		{name: "remove queue item", method: "DELETE", path: "/admin/queue/queued", token: "secret", status: http.StatusOK},
+		{name: "remove missing queue item", method: "DELETE", path: "/admin/queue/queued", token: "secret", status: http.StatusNotFound},
+		{name: "reset missing limit", method: "DELETE", path: "/admin/limits/127.0.0.1", token: "secret", status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("%s %s status = %d, want %d: %s", tt.method, tt.path, rec.Code, tt.status, rec.Body.String())
+			}
+		})
+	}
+
+	req := httptest.NewRequest("GET", "/admin/status", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	var status adminStatusResponse
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+	want := adminStatusResponse{Paused: true, Payout: 3, Interval: 60, Queued: 0}
+	if status != want {
+		t.Errorf("status = %+v, want %+v", status, want)
+	}
+}
+
+func TestAdminRouterNetworks(t *testing.T) {
+	cfg := testConfig()
+	cfg.Admin.Token = "secret"
+	s := newTestServer(t, &mockTxBuilder{}, cfg)
+	sepolia := newTestServer(t, &mockTxBuilder{}, testConfig())
+	s.AddNetwork("sepolia", sepolia)
+	sepolia.queue.push(&queueItem{ID: "queued", Address: "0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B", Class: publicClassName, Amount: chain.EtherToWei(1)})
+	router := s.setupAdminRouter()
+	serve := func(method, path, body string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := serve("POST", "/admin/sepolia/pause", ""); code != http.StatusOK || !sepolia.isPaused() || s.isPaused() {
+		t.Errorf("pause of sepolia = %d, paused %t and default paused %t", code, sepolia.isPaused(), s.isPaused())
+	}
+	if code := serve("PUT", "/admin/sepolia/config", `{"payout":5}`); code != http.StatusOK || sepolia.cfg.Payout() != 5 || s.cfg.Payout() == 5 {
+		t.Errorf("config of sepolia = %d, payout %d and default payout %d", code, sepolia.cfg.Payout(), s.cfg.Payout())
+	}
+	if code := serve("DELETE", "/admin/queue/queued", ""); code != http.StatusNotFound {
+		t.Errorf("removing a sepolia item from the default queue = %d, want %d", code, http.StatusNotFound)
+	}
+	if code := serve("DELETE", "/admin/sepolia/queue/queued", ""); code != http.StatusOK || sepolia.queue.len() != 0 {
+		t.Errorf("removing a sepolia item = %d, queued %d", code, sepolia.queue.len())
+	}
+	if code := serve("GET", "/admin/goerli/status", ""); code != http.StatusNotFound {
+		t.Errorf("status of an unknown network = %d, want %d", code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/chainflag/eth-faucet/internal/audit"
+)
+
+const (
+	identityAnonymous = "anonymous"
+	identityAdmin     = "admin"
+)
+
+type clientIPContextKey struct{}
+
+func withClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPContextKey{}, ip)
+}
+
+func clientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPContextKey{}).(string)
+	return ip
+}
+
+// claimIdentity names who authorized a claim in the audit log.
+func claimIdentity(ctx context.Context) string {
+	if key := apiKeyFromContext(ctx); key != nil {
+		return "apikey:" + key.ID
+	}
+	return identityAnonymous
+}
+
+// hashClientIP returns the hash of a client IP that is kept with queued items
+// for the audit log. The IP itself is never stored.
+func (s *Server) hashClientIP(ip string) string {
+	if s.audit == nil {
+		return ""
+	}
+	return s.audit.HashIP(ip)
+}
+
+// hashHex returns the hex of a sent transaction's hash, or an empty string if
+// the transfer failed.
+func hashHex(txHash common.Hash, err error) string {
+	if err != nil {
+		return ""
+	}
+	return txHash.Hex()
+}
+
+// recordPayout writes the result of a transfer to the audit log. A failure to
+// write is logged, since the transfer has already been sent.
+func (s *Server) recordPayout(item *queueItem, txHash string, err error) {
+	if s.audit == nil {
+		return
+	}
+	record := audit.Record{
+		Network:      s.networkLabel(),
+		ClaimID:      item.ID,
+		Recipient:    item.Address,
+		Amount:       item.Amount.String(),
+		TxHash:       txHash,
+		ClientIPHash: item.ClientIPHash,
+		Identity:     item.Identity,
+		Outcome:      audit.OutcomeSent,
+	}
+	if err != nil {
+		record.Outcome, record.Error = audit.OutcomeFailed, err.Error()
+	}
+	if err := s.audit.Append(record); err != nil {
+		log.WithError(err).WithField("id", item.ID).Error("Failed to write the audit log")
+	}
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/urfave/negroni"
+
+	"github.com/chainflag/eth-faucet/internal/audit"
+)
+
+func TestAuditPayouts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	auditLog, err := audit.Open(path, "salt")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	s := newTestServer(t, &mockTxBuilder{}, testConfig())
+	s.audit = auditLog
+
+	// Funded directly through the HTTP API
+	req := httptest.NewRequest("POST", "/api/claim", strings.NewReader(`{"address":"0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B"}`))
+	req.RemoteAddr = "192.0.2.1:1234"
+	negroni.New(negroni.Wrap(s.setupRouter())).ServeHTTP(httptest.NewRecorder(), req)
+
+	// Sent from the queue
+	s.mutex.Lock()
+	ctx := withClientIP(context.Background(), "192.0.2.2")
+	if _, _, err := s.claim(ctx, "0x7ef5a6135f1fd6a02593eedc869c6d41d934aef8"); err != nil {
+		t.Fatalf("claim() error = %v", err)
+	}
+	s.drainQueue(context.Background())
+	s.mutex.Unlock()
+	auditLog.Close()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var records []audit.Record
+	audit.Read(f, func(r audit.Record) error {
+		records = append(records, r)
+		return nil
+	})
+	if len(records) != 2 {
+		t.Fatalf("audit log has %d records, want 2", len(records))
+	}
+	for i, ip := range []string{"192.0.2.1", "192.0.2.2"} {
+		r := records[i]
+		if r.Outcome != audit.OutcomeSent || r.TxHash == "" || r.Identity != identityAnonymous || r.ClientIPHash != auditLog.HashIP(ip) || r.Amount != "1000000000000000000" {
+			t.Errorf("record %d = %+v, want a sent payout from %s", i, r, ip)
+		}
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := audit.Verify(f); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/chainflag/eth-faucet/internal/apikey"
+)
+
+type apiKeyContextKey struct{}
+
+func withAPIKey(ctx context.Context, key *apikey.Key) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey{}, key)
+}
+
+func apiKeyFromContext(ctx context.Context) *apikey.Key {
+	key, _ := ctx.Value(apiKeyContextKey{}).(*apikey.Key)
+	return key
+}
+
+// authenticate resolves the API key of a request carrying an Authorization
+// header. Requests without the header continue anonymously, as do all
+// requests without a key store, whose header may be meant for a proxy in
+// front of the faucet, e.g. for basic auth.
+func (s *Server) authenticate(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	header := r.Header.Get("Authorization")
+	if header == "" || s.keys == nil {
+		next(w, r)
+		return
+	}
+	key, err := s.authorize(header)
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+	next(w, r.WithContext(withAPIKey(r.Context(), key)))
+}
+
+// authorize looks up the API key of a bearer authorization header.
+func (s *Server) authorize(header string) (*apikey.Key, *apiError) {
+	if s.keys == nil || !strings.HasPrefix(header, "Bearer ") {
+		return nil, newAPIError(ErrUnauthorized, "Invalid authorization header")
+	}
+
+	key, err := s.keys.Authenticate(strings.TrimSpace(strings.TrimPrefix(header, "Bearer ")))
+	switch {
+	case errors.Is(err, apikey.ErrInvalidKey):
+		return nil, newAPIError(ErrInvalidAPIKey, err.Error())
+	case errors.Is(err, apikey.ErrExpiredKey):
+		return nil, newAPIError(ErrAPIKeyExpired, err.Error())
+	case err != nil:
+		log.WithError(err).Error("Failed to look up API key")
+		return nil, internalError()
+	}
+	if !key.AllowsToken(apikey.NativeToken) {
+		return nil, newAPIError(ErrTokenNotAllowed, "API key is not allowed to claim %s", apikey.NativeToken)
+	}
+	return key, nil
+}
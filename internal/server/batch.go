@@ -0,0 +1,206 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/chainflag/eth-faucet/internal/chain"
+)
+
+const (
+	batchBodyLimit = 64 * 1024
+	batchChunkSize = 50
+)
+
+// handleBatchClaim queues transfers to many addresses at once. It is only
+// available to API keys and, through the admin listener, to admins.
+func (s *Server) handleBatchClaim(admin bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.NotFound(w, r)
+			return
+		}
+		key := apiKeyFromContext(r.Context())
+		if !admin && key == nil {
+			renderError(w, r, newAPIError(ErrUnauthorized, "Batch claims require an API key"))
+			return
+		}
+		if err := s.acceptError(); err != nil {
+			renderError(w, r, err)
+			return
+		}
+
+		var req batchClaimRequest
+		if err := decodeJSONBodyLimit(r, &req, batchBodyLimit); err != nil {
+			renderReadError(w, r, err)
+			return
+		}
+		if len(req.Claims) == 0 || len(req.Claims) > s.cfg.QueueCap {
+			msg := fmt.Sprintf("Batch must contain between 1 and %d claims", s.cfg.QueueCap)
+			renderError(w, r, newAPIError(ErrInvalidBatch, msg))
+			return
+		}
+
+		class := s.classifyKey(key)
+		if class == nil {
+			class = s.publicClass()
+		}
+		maxAmount := class.payoutWei()
+		if key != nil && key.Payout > 0 {
+			maxAmount = chain.EtherToWei(int64(key.Payout))
+		}
+
+		batchID := uuid.NewString()
+		clientIPHash := s.hashClientIP(getClientIPFromRequest(s.cfg.ProxyCount, r))
+		identity := claimIdentity(r.Context())
+		if admin {
+			identity = identityAdmin
+		}
+		items := make([]*queueItem, 0, len(req.Claims))
+		statuses := make([]batchClaimStatus, len(req.Claims))
+		invalid := false
+		for i, claim := range req.Claims {
+			statuses[i] = batchClaimStatus{Address: claim.Address, Status: "invalid"}
+			if !chain.IsValidAddress(claim.Address, true) {
+				statuses[i].Code, statuses[i].Error = ErrInvalidAddress, "invalid address"
+				invalid = true
+				continue
+			}
+			amount := maxAmount
+			if strings.TrimSpace(claim.Amount) != "" {
+				parsed, err := chain.ParseEther(strings.TrimSpace(claim.Amount))
+				if err != nil || parsed.Sign() <= 0 {
+					statuses[i].Code, statuses[i].Error = ErrInvalidAmount, "invalid amount"
+					invalid = true
+					continue
+				}
+				// Only admins may send more than the payout of the key
+				if !admin && parsed.Cmp(maxAmount) > 0 {
+					statuses[i].Code, statuses[i].Error = ErrInvalidAmount, "amount exceeds payout"
+					invalid = true
+					continue
+				}
+				amount = parsed
+			}
+			items = append(items, &queueItem{
+				ID:           uuid.NewString(),
+				Address:      claim.Address,
+				Class:        class.Name,
+				Amount:       amount,
+				Batch:        batchID,
+				ClientIPHash: clientIPHash,
+				Identity:     identity,
+				RequestID:    requestIDFromContext(r.Context()),
+			})
+			statuses[i] = batchClaimStatus{ID: items[len(items)-1].ID, Address: claim.Address, Amount: amount.String(), Status: statusQueued}
+		}
+		if invalid {
+			renderJSON(w, batchClaimResponse{Message: "Batch contains invalid claims", Code: ErrInvalidBatch, Claims: statuses}, ErrInvalidBatch.HTTPStatus())
+			return
+		}
+
+		if key != nil && !admin {
+			limit, err := s.limiter.reserveQuota(key, len(items))
+			if limit != nil {
+				limit.setHeaders(w)
+			}
+			if err != nil {
+				renderError(w, r, err)
+				return
+			}
+		}
+		ctx, span := tracer.Start(r.Context(), "queue.enqueue", trace.WithAttributes(
+			attribute.String("faucet.class", class.Name),
+			attribute.Int("faucet.batch_size", len(items)),
+		))
+		carrier := injectTrace(ctx)
+		for _, item := range items {
+			item.Trace = carrier
+		}
+		if !s.queue.pushAll(class.Name, items) {
+			endSpan(span, errQueueFull)
+			if key != nil && !admin {
+				s.limiter.returnQuota(key, len(items))
+			}
+			logger(ctx).WithField("class", class.Name).Warn("Max queue capacity reached")
+			renderError(w, r, errQueueFull)
+			return
+		}
+		span.End()
+		for _, item := range items {
+			s.tracker.report(item, statusQueued, "", nil)
+		}
+		claimsTotal.WithLabelValues(s.networkLabel(), outcomeQueued).Add(float64(len(items)))
+		s.observeQueue()
+
+		logger(ctx).WithFields(log.Fields{
+			"batch": batchID,
+			"count": len(items),
+			"class": class.Name,
+		}).Info("Added batch to queue successfully")
+		resp := batchClaimResponse{
+			Message: fmt.Sprintf("Added %d claims to the queue", len(items)),
+			BatchID: batchID,
+			Claims:  statuses,
+		}
+		renderJSON(w, resp, http.StatusOK)
+	}
+}
+
+// transferBatch sends the items through the batched payout path of the builder.
+func (s *Server) transferBatch(ctx context.Context, batcher chain.BatchTxBuilder, items []*queueItem) {
+	to := make([]string, len(items))
+	values := make([]*big.Int, len(items))
+	for i, item := range items {
+		to[i], values[i] = item.Address, item.Amount
+	}
+
+	// The batch continues the trace of its first claim and links the others
+	var links []trace.Link
+	for _, item := range items[1:] {
+		if sc := trace.SpanContextFromContext(extractTrace(ctx, item)); sc.IsValid() {
+			links = append(links, trace.Link{SpanContext: sc})
+		}
+	}
+	ctx = withRequestID(ctx, items[0].RequestID)
+	ctx, span := tracer.Start(extractTrace(ctx, items[0]), "queue.transfer", trace.WithLinks(links...), trace.WithAttributes(
+		attribute.String("faucet.class", items[0].Class),
+		attribute.Int("faucet.batch_size", len(items)),
+	))
+	txHashes, err := batcher.BatchTransfer(ctx, to, values)
+	if err != nil {
+		endSpan(span, transferError(err))
+	} else {
+		span.End()
+	}
+	for i, item := range items {
+		if i < len(txHashes) {
+			s.recordPayout(item, txHashes[i].Hex(), nil)
+			s.recordHistory(item)
+			s.tracker.report(item, statusBroadcast, txHashes[i].Hex(), nil)
+		} else {
+			s.recordPayout(item, "", err)
+			s.tracker.report(item, statusFailed, "", transferError(err))
+		}
+	}
+	queueTransfers.WithLabelValues(s.networkLabel(), "sent").Add(float64(len(txHashes)))
+	queueTransfers.WithLabelValues(s.networkLabel(), "failed").Add(float64(len(items) - len(txHashes)))
+	fields := log.Fields{
+		"batch": items[0].Batch,
+		"sent":  len(txHashes),
+		"total": len(items),
+	}
+	if err != nil {
+		logger(ctx).WithError(err).WithFields(fields).Error("Failed to handle batch in the queue")
+		return
+	}
+	logger(ctx).WithFields(fields).Info("Consume batch from queue successfully")
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/urfave/negroni"
+
+	"github.com/chainflag/eth-faucet/internal/apikey"
+)
+
+type mockBatchTxBuilder struct {
+	mockTxBuilder
+	batches int
+}
+
+func (m *mockBatchTxBuilder) BatchTransfer(ctx context.Context, to []string, values []*big.Int) ([]common.Hash, error) {
+	m.batches++
+	hashes := make([]common.Hash, 0, len(to))
+	for i := range to {
+		txHash, _ := m.Transfer(ctx, to[i], values[i])
+		hashes = append(hashes, txHash)
+	}
+	return hashes, nil
+}
+
+func TestHandleBatchClaim(t *testing.T) {
+	store, err := apikey.Open(filepath.Join(t.TempDir(), "apikeys.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, _, err := store.Create(apikey.Key{Name: "loadtest", Payout: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	builder := &mockBatchTxBuilder{}
+	s := newTestServer(t, builder, testConfig())
+	s.keys = store
+	handler := negroni.New(negroni.HandlerFunc(s.authenticate), negroni.Wrap(s.handleBatchClaim(false)))
+
+	tests := []struct {
+		name   string
+		auth   string
+		body   string
+		status int
+		queued int
+	}{
+		{name: "anonymous", body: `{"claims":[{"address":"0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B"}]}`, status: http.StatusUnauthorized},
+		{name: "invalid address", auth: secret, body: `{"claims":[{"address":"0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B"},{"address":"0xinvalid"}]}`, status: http.StatusBadRequest},
+		{name: "amount exceeds payout", auth: secret, body: `{"claims":[{"address":"0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B","amount":"3"}]}`, status: http.StatusBadRequest},
+		{name: "too large", auth: secret, body: `{"claims":[` + strings.Repeat(" ", batchBodyLimit) + `]}`, status: http.StatusRequestEntityTooLarge},
+		{name: "queued", auth: secret, body: `{"claims":[{"address":"0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B","amount":"0.5"},{"address":"0x4B0897b0513fdC7C541B6d9D7E929C4e5364D2dB"}]}`, status: http.StatusOK, queued: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/claim/batch", strings.NewReader(tt.body))
+			if tt.auth != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("ServeHTTP() status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			if s.queue.len() != tt.queued {
+				t.Errorf("queue length = %d, want %d", s.queue.len(), tt.queued)
+			}
+			if tt.status == http.StatusOK {
+				var resp batchClaimResponse
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatal(err)
+				}
+				if resp.BatchID == "" || len(resp.Claims) != tt.queued {
+					t.Errorf("response = %+v, want batch ID and %d claims", resp, tt.queued)
+				}
+			}
+		})
+	}
+
+	s.drainQueue(context.Background())
+	if builder.batches != 1 || len(builder.sent()) != 2 {
+		t.Errorf("drainQueue() sent %d transfers in %d batches, want 2 in 1", len(builder.sent()), builder.batches)
+	}
+}
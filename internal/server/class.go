@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/chainflag/eth-faucet/internal/chain"
+)
+
+const (
+	publicClassName    = "public"
+	defaultClassWeight = 4
+)
+
+// ClaimClass is a priority lane for claimants that are recognised by the faucet.
+// Zero values for Payout and Interval fall back to the public settings.
+type ClaimClass struct {
+	Name      string   `json:"name" yaml:"name" toml:"name"`
+	Weight    int      `json:"weight" yaml:"weight" toml:"weight"`
+	Payout    int      `json:"payout" yaml:"payout" toml:"payout"`
+	Interval  int      `json:"minutes" yaml:"minutes" toml:"minutes"`
+	Addresses []string `json:"addresses" yaml:"addresses" toml:"addresses"`
+
+	members map[common.Address]bool
+	cfg     *Config
+}
+
+func LoadClaimClasses(path string) ([]ClaimClass, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var classes []ClaimClass
+	if err := json.Unmarshal(data, &classes); err != nil {
+		return nil, err
+	}
+	if err := validateClasses(classes); err != nil {
+		return nil, err
+	}
+	return classes, nil
+}
+
+func validateClasses(classes []ClaimClass) error {
+	seen := map[string]bool{publicClassName: true}
+	for i := range classes {
+		name := strings.ToLower(classes[i].Name)
+		if name == "" || seen[name] {
+			return fmt.Errorf("claim class %d has a missing or duplicate name %q", i, classes[i].Name)
+		}
+		seen[name] = true
+		for _, address := range classes[i].Addresses {
+			if !chain.IsValidAddress(address, false) {
+				return fmt.Errorf("claim class %s has an invalid address %q", name, address)
+			}
+		}
+	}
+	return nil
+}
+
+func newPublicClass(cfg *Config) *ClaimClass {
+	return &ClaimClass{
+		Name:   publicClassName,
+		Weight: 1,
+		cfg:    cfg,
+	}
+}
+
+// resolveClasses fills in the default weight and indexes the allowlists.
+func resolveClasses(cfg *Config, defs []ClaimClass) []*ClaimClass {
+	classes := make([]*ClaimClass, 0, len(defs))
+	for i := range defs {
+		class := defs[i]
+		class.Name = strings.ToLower(class.Name)
+		if class.Weight <= 0 {
+			class.Weight = defaultClassWeight
+		}
+		class.cfg = cfg
+		class.members = make(map[common.Address]bool, len(class.Addresses))
+		for _, address := range class.Addresses {
+			class.members[common.HexToAddress(address)] = true
+		}
+		classes = append(classes, &class)
+	}
+	return classes
+}
+
+func (c *ClaimClass) isPublic() bool {
+	return c.Name == publicClassName
+}
+
+func (c *ClaimClass) payoutWei() *big.Int {
+	if c.Payout > 0 {
+		return chain.EtherToWei(int64(c.Payout))
+	}
+	return chain.EtherToWei(int64(c.cfg.Payout()))
+}
+
+func (c *ClaimClass) ttl() time.Duration {
+	if c.Interval > 0 {
+		return time.Duration(c.Interval) * time.Minute
+	}
+	return time.Duration(c.cfg.Interval()) * time.Minute
+}
+
+// limitKey namespaces limiter keys so that each class is rate limited on its own.
+func (c *ClaimClass) limitKey(key string) string {
+	if c.isPublic() {
+		return key
+	}
+	return c.Name + "/" + key
+}
+
+type classContextKey struct{}
+
+func withClaimClass(ctx context.Context, class *ClaimClass) context.Context {
+	return context.WithValue(ctx, classContextKey{}, class)
+}
+
+func claimClassFromContext(ctx context.Context) *ClaimClass {
+	class, _ := ctx.Value(classContextKey{}).(*ClaimClass)
+	return class
+}
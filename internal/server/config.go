@@ -1,21 +1,451 @@
 package server
 
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/acme/autocert"
+	"gopkg.in/yaml.v3"
+
+	"github.com/chainflag/eth-faucet/internal/chain"
+)
+
+// Config holds the settings of the faucet. The groups match the prefixes of
+// the command line flags, e.g. faucet.amount is Faucet.Amount.
 type Config struct {
-	network    string
-	httpPort   int
-	interval   int
-	payout     int
-	proxyCount int
-	queueCap   int
+	mutex sync.RWMutex
+
+	HTTPAddr        string `yaml:"httpaddr" toml:"httpaddr"`
+	HTTPPort        int    `yaml:"httpport" toml:"httpport"`
+	ProxyCount      int    `yaml:"proxycount" toml:"proxycount"`
+	QueueCap        int    `yaml:"queuecap" toml:"queuecap"`
+	QueueFile       string `yaml:"queuefile" toml:"queuefile"`
+	ShutdownTimeout int    `yaml:"shutdowntimeout" toml:"shutdowntimeout"`
+
+	Faucet  FaucetConfig  `yaml:"faucet" toml:"faucet"`
+	Wallet  WalletConfig  `yaml:"wallet" toml:"wallet"`
+	APIKey  APIKeyConfig  `yaml:"apikey" toml:"apikey"`
+	Admin   AdminConfig   `yaml:"admin" toml:"admin"`
+	Feed    FeedConfig    `yaml:"feed" toml:"feed"`
+	GRPC    GRPCConfig    `yaml:"grpc" toml:"grpc"`
+	Metrics MetricsConfig `yaml:"metrics" toml:"metrics"`
+	Monitor MonitorConfig `yaml:"monitor" toml:"monitor"`
+	Tracing TracingConfig `yaml:"tracing" toml:"tracing"`
+	Log     LogConfig     `yaml:"log" toml:"log"`
+	Audit   AuditConfig   `yaml:"audit" toml:"audit"`
+	Stats   StatsConfig   `yaml:"stats" toml:"stats"`
+	TLS     TLSConfig     `yaml:"tls" toml:"tls"`
+	HTTP    HTTPConfig    `yaml:"http" toml:"http"`
+
+	// Chains maps network names to the chain ID their provider must report
+	Chains map[string]int64 `yaml:"chains" toml:"chains"`
+	// Networks are served in addition to the default network below /api/{name}/
+	Networks []NetworkConfig `yaml:"networks" toml:"networks"`
+}
+
+type FaucetConfig struct {
+	Amount      int          `yaml:"amount" toml:"amount"`
+	Minutes     int          `yaml:"minutes" toml:"minutes"`
+	Name        string       `yaml:"name" toml:"name"`
+	ClassesFile string       `yaml:"classesfile" toml:"classesfile"`
+	Classes     []ClaimClass `yaml:"classes" toml:"classes"`
+}
+
+type WalletConfig struct {
+	KeyJSON  string `yaml:"keyjson" toml:"keyjson"`
+	KeyPass  string `yaml:"keypass" toml:"keypass"`
+	PrivKey  string `yaml:"privkey" toml:"privkey"`
+	Provider string `yaml:"provider" toml:"provider"`
+	ChainID  int64  `yaml:"chainid" toml:"chainid"`
+}
+
+type APIKeyConfig struct {
+	File string `yaml:"file" toml:"file"`
+}
+
+// AdminConfig sets up the admin listener. Like every listener address, Addr
+// is the interface to bind, all if empty, or unix:/path for a Unix socket.
+type AdminConfig struct {
+	Addr  string `yaml:"addr" toml:"addr"`
+	Port  int    `yaml:"port" toml:"port"`
+	Token string `yaml:"token" toml:"token"`
+}
+
+type FeedConfig struct {
+	Redact string `yaml:"redact" toml:"redact"`
+}
+
+type GRPCConfig struct {
+	Addr string `yaml:"addr" toml:"addr"`
+	Port int    `yaml:"port" toml:"port"`
+}
+
+// MetricsConfig serves /metrics on a listener of its own instead of the
+// public one.
+type MetricsConfig struct {
+	Addr string `yaml:"addr" toml:"addr"`
+	Port int    `yaml:"port" toml:"port"`
+}
+
+// MonitorConfig sets up the balance monitor. Webhooks and thresholds are comma
+// separated lists, and the thresholds and the floor are amounts of Ether.
+type MonitorConfig struct {
+	Webhooks   string  `yaml:"webhooks" toml:"webhooks"`
+	Thresholds string  `yaml:"thresholds" toml:"thresholds"`
+	Floor      float64 `yaml:"floor" toml:"floor"`
 }
 
-func NewConfig(network string, httpPort, interval, payout, proxyCount, queueCap int) *Config {
+// TracingConfig sets up the export of spans over OTLP/HTTP, e.g. to
+// http://localhost:4318. Tracing is off without an endpoint.
+type TracingConfig struct {
+	Endpoint string  `yaml:"endpoint" toml:"endpoint"`
+	Ratio    float64 `yaml:"ratio" toml:"ratio"`
+}
+
+// LogConfig sets the log format, text or json, and the minimum log level.
+type LogConfig struct {
+	Format string `yaml:"format" toml:"format"`
+	Level  string `yaml:"level" toml:"level"`
+}
+
+// AuditConfig sets up the audit log of payouts, which is shared by all
+// networks. The salt keys the hashes of client IPs.
+type AuditConfig struct {
+	File string `yaml:"file" toml:"file"`
+	Salt string `yaml:"salt" toml:"salt"`
+}
+
+// StatsConfig sets the JSONL file that the claims of all networks are kept in
+// for the public statistics.
+type StatsConfig struct {
+	File string `yaml:"file" toml:"file"`
+}
+
+// TLSConfig serves the HTTP, admin and gRPC listeners over TLS, either with a
+// static certificate or with certificates for the comma separated domains
+// obtained from the ACME directory. The redirect port serves a redirect to
+// HTTPS and the HTTP challenges of ACME.
+type TLSConfig struct {
+	CertFile     string `yaml:"certfile" toml:"certfile"`
+	KeyFile      string `yaml:"keyfile" toml:"keyfile"`
+	Domains      string `yaml:"domains" toml:"domains"`
+	Email        string `yaml:"email" toml:"email"`
+	Directory    string `yaml:"directory" toml:"directory"`
+	CacheDir     string `yaml:"cachedir" toml:"cachedir"`
+	RedirectPort int    `yaml:"redirectport" toml:"redirectport"`
+}
+
+// HTTPConfig sets the timeouts of the HTTP listeners in seconds, 0 for none.
+type HTTPConfig struct {
+	ReadTimeout       int `yaml:"readtimeout" toml:"readtimeout"`
+	ReadHeaderTimeout int `yaml:"readheadertimeout" toml:"readheadertimeout"`
+	WriteTimeout      int `yaml:"writetimeout" toml:"writetimeout"`
+	IdleTimeout       int `yaml:"idletimeout" toml:"idletimeout"`
+}
+
+// NetworkConfig describes an additional network. Its provider and chain ID
+// are its own, while unset faucet and signer settings fall back to the ones
+// of the default network.
+type NetworkConfig struct {
+	Name      string        `yaml:"name" toml:"name"`
+	QueueFile string        `yaml:"queuefile" toml:"queuefile"`
+	Faucet    FaucetConfig  `yaml:"faucet" toml:"faucet"`
+	Wallet    WalletConfig  `yaml:"wallet" toml:"wallet"`
+	Monitor   MonitorConfig `yaml:"monitor" toml:"monitor"`
+}
+
+var (
+	networkNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+	// reservedNetworkNames are taken by the routes of the default network
+	reservedNetworkNames = map[string]bool{
+		"claim": true, "info": true, "ws": true, "rpc": true, "v1": true, "networks": true, "stats": true,
+		"status": true, "pause": true, "resume": true, "config": true, "queue": true, "limits": true, "claims": true,
+	}
+)
+
+func DefaultConfig() *Config {
 	return &Config{
-		network:    network,
-		httpPort:   httpPort,
-		interval:   interval,
-		payout:     payout,
-		proxyCount: proxyCount,
-		queueCap:   queueCap,
+		HTTPPort:        8080,
+		QueueCap:        100,
+		ShutdownTimeout: 30,
+		Faucet: FaucetConfig{
+			Amount:  1,
+			Minutes: 1440,
+			Name:    "testnet",
+		},
+		Wallet: WalletConfig{
+			KeyPass: "password.txt",
+		},
+		Feed: FeedConfig{
+			Redact: RedactPartial,
+		},
+		Tracing: TracingConfig{
+			Ratio: 1,
+		},
+		Log: LogConfig{
+			Format: "text",
+			Level:  "info",
+		},
+		TLS: TLSConfig{
+			Directory: autocert.DefaultACMEDirectory,
+			CacheDir:  "acme",
+		},
+		HTTP: HTTPConfig{
+			ReadTimeout:       30,
+			ReadHeaderTimeout: 10,
+			WriteTimeout:      30,
+			IdleTimeout:       120,
+		},
+		Chains: map[string]int64{
+			"goerli":  5,
+			"sepolia": 11155111,
+		},
+	}
+}
+
+// ForNetwork returns the settings of an additional network. It listens on no
+// ports of its own.
+func (c *Config) ForNetwork(n NetworkConfig) *Config {
+	sub := &Config{
+		ProxyCount:      c.ProxyCount,
+		QueueCap:        c.QueueCap,
+		QueueFile:       n.QueueFile,
+		ShutdownTimeout: c.ShutdownTimeout,
+		Faucet:          c.Faucet,
+		Wallet:          c.Wallet,
+		APIKey:          c.APIKey,
+		Feed:            c.Feed,
+		Monitor:         c.Monitor,
+		Chains:          c.Chains,
+	}
+	if n.Monitor != (MonitorConfig{}) {
+		sub.Monitor = n.Monitor
+	}
+	sub.Faucet.Name = n.Name
+	if n.Faucet.Name != "" {
+		sub.Faucet.Name = n.Faucet.Name
 	}
+	if n.Faucet.Amount != 0 {
+		sub.Faucet.Amount = n.Faucet.Amount
+	}
+	if n.Faucet.Minutes != 0 {
+		sub.Faucet.Minutes = n.Faucet.Minutes
+	}
+	if n.Faucet.ClassesFile != "" || len(n.Faucet.Classes) > 0 {
+		sub.Faucet.ClassesFile = n.Faucet.ClassesFile
+		sub.Faucet.Classes = n.Faucet.Classes
+	}
+	if n.Wallet.PrivKey != "" || n.Wallet.KeyJSON != "" {
+		sub.Wallet.PrivKey = n.Wallet.PrivKey
+		sub.Wallet.KeyJSON = n.Wallet.KeyJSON
+		if n.Wallet.KeyPass != "" {
+			sub.Wallet.KeyPass = n.Wallet.KeyPass
+		}
+	}
+	sub.Wallet.Provider = n.Wallet.Provider
+	sub.Wallet.ChainID = n.Wallet.ChainID
+	if sub.Wallet.ChainID == 0 {
+		sub.Wallet.ChainID = c.Chains[n.Name]
+	}
+	return sub
+}
+
+// ChainID returns the chain ID the provider must report, or 0 if the network
+// name is not known.
+func (c *Config) ChainID() int64 {
+	if c.Wallet.ChainID > 0 {
+		return c.Wallet.ChainID
+	}
+	return c.Chains[strings.ToLower(c.Faucet.Name)]
+}
+
+// LoadFile reads a YAML or TOML file, chosen by its extension, on top of the
+// current settings. Unknown keys are rejected.
+func (c *Config) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), c)
+		if err != nil {
+			return fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("invalid config file %s: unknown key %s", path, undecoded[0])
+		}
+	default:
+		return fmt.Errorf("unsupported config file format %q", filepath.Ext(path))
+	}
+	return nil
+}
+
+// Validate checks the settings that can be verified without connecting to
+// the network and reports every problem at once.
+func (c *Config) Validate() error {
+	problems := c.problems()
+	names := make(map[string]bool)
+	queueFiles := map[string]bool{c.QueueFile: c.QueueFile != ""}
+	for i, n := range c.Networks {
+		prefix := fmt.Sprintf("networks[%d]", i)
+		if n.Name != "" {
+			prefix = "networks." + n.Name
+		}
+		switch {
+		case !networkNamePattern.MatchString(n.Name):
+			problems = append(problems, prefix+": name must consist of lowercase letters, digits and dashes")
+		case reservedNetworkNames[n.Name] || names[n.Name]:
+			problems = append(problems, prefix+": name is reserved or used twice")
+		}
+		names[n.Name] = true
+		if n.QueueFile != "" && queueFiles[n.QueueFile] {
+			problems = append(problems, prefix+": queuefile is used twice")
+		}
+		queueFiles[n.QueueFile] = true
+		for _, problem := range c.ForNetwork(n).problems() {
+			problems = append(problems, prefix+": "+problem)
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func (c *Config) problems() []string {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.Faucet.Amount > 0, "faucet.amount must be positive")
+	check(c.Faucet.Minutes >= 0, "faucet.minutes must not be negative")
+	check(c.Faucet.Name != "", "faucet.name must not be empty")
+	check(c.QueueCap > 0, "queuecap must be positive")
+	check(c.ProxyCount >= 0, "proxycount must not be negative")
+	check(c.ShutdownTimeout >= 0, "shutdowntimeout must not be negative")
+	for _, port := range []struct {
+		name  string
+		value int
+	}{{"httpport", c.HTTPPort}, {"admin.port", c.Admin.Port}, {"grpc.port", c.GRPC.Port}, {"metrics.port", c.Metrics.Port}, {"tls.redirectport", c.TLS.RedirectPort}} {
+		check(port.value >= 0 && port.value <= 65535, "%s must be between 0 and 65535", port.name)
+	}
+	check(!listenerEnabled(c.Admin.Addr, c.Admin.Port) || c.Admin.Token != "", "the admin listener requires admin.token")
+	// A socket file of another listener would be taken for a stale one and removed
+	sockets := make(map[string]bool)
+	for _, addr := range []string{c.HTTPAddr, c.Admin.Addr, c.Metrics.Addr, c.GRPC.Addr} {
+		if strings.HasPrefix(addr, unixPrefix) {
+			check(!sockets[addr], "%s is used by more than one listener", addr)
+			sockets[addr] = true
+		}
+	}
+	check(c.Wallet.Provider != "", "wallet.provider is required")
+	check(c.Wallet.PrivKey != "" || c.Wallet.KeyJSON != "", "wallet.privkey or wallet.keyjson is required")
+	check(c.Wallet.ChainID >= 0, "wallet.chainid must not be negative")
+	if _, err := ParseRedaction(c.Feed.Redact); err != nil {
+		problems = append(problems, "feed.redact: "+err.Error())
+	}
+	if err := validateClasses(c.Faucet.Classes); err != nil {
+		problems = append(problems, "faucet.classes: "+err.Error())
+	}
+	if _, err := parseThresholds(c.Monitor.Thresholds); err != nil {
+		problems = append(problems, "monitor.thresholds: "+err.Error())
+	}
+	for _, webhook := range chain.SplitList(c.Monitor.Webhooks) {
+		u, err := url.Parse(webhook)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "monitor.webhooks: invalid URL %q", webhook)
+	}
+	if c.Monitor.Floor < 0 {
+		problems = append(problems, "monitor.floor must not be negative")
+	} else if _, err := parseFloor(c.Monitor.Floor); err != nil {
+		problems = append(problems, "monitor.floor: "+err.Error())
+	}
+	if c.Tracing.Endpoint != "" {
+		u, err := url.Parse(c.Tracing.Endpoint)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "tracing.endpoint: invalid URL %q", c.Tracing.Endpoint)
+	}
+	check(c.Tracing.Ratio >= 0 && c.Tracing.Ratio <= 1, "tracing.ratio must be between 0 and 1")
+	// Without a salt, the hash of an IPv4 address is reversed by trying all of them
+	check(c.Audit.File == "" || c.Audit.Salt != "", "audit.file requires audit.salt")
+	// Additional networks leave the log settings of the process unset
+	if c.Log != (LogConfig{}) {
+		format := strings.ToLower(c.Log.Format)
+		check(format == "text" || format == "json", "log.format must be text or json")
+		_, err := log.ParseLevel(c.Log.Level)
+		check(err == nil, "log.level must be one of trace, debug, info, warn, error, fatal or panic")
+	}
+	check((c.TLS.CertFile == "") == (c.TLS.KeyFile == ""), "tls.certfile and tls.keyfile must be set together")
+	check(c.TLS.CertFile == "" || c.TLS.Domains == "", "tls.certfile and tls.domains must not be set together")
+	if c.TLS.Domains != "" {
+		u, err := url.Parse(c.TLS.Directory)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "tls.directory: invalid URL %q", c.TLS.Directory)
+		check(c.TLS.CacheDir != "", "tls.cachedir must not be empty")
+	}
+	check(c.TLS.RedirectPort == 0 || c.TLS.CertFile != "" || c.TLS.Domains != "", "tls.redirectport requires tls.certfile or tls.domains")
+	check(c.HTTP.ReadTimeout >= 0 && c.HTTP.ReadHeaderTimeout >= 0 && c.HTTP.WriteTimeout >= 0 && c.HTTP.IdleTimeout >= 0, "http timeouts must not be negative")
+	chains := make([]string, 0, len(c.Chains))
+	for name := range c.Chains {
+		chains = append(chains, name)
+	}
+	sort.Strings(chains)
+	for _, name := range chains {
+		check(c.Chains[name] > 0, "chains.%s must be positive", name)
+	}
+	return problems
+}
+
+// Network returns the network name displayed on the frontend.
+func (c *Config) Network() string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.Faucet.Name
+}
+
+// Payout returns the number of Ethers transferred per public claim.
+func (c *Config) Payout() int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.Faucet.Amount
+}
+
+func (c *Config) SetPayout(payout int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.Faucet.Amount = payout
+}
+
+// Interval returns the number of minutes between public funding rounds.
+func (c *Config) Interval() int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.Faucet.Minutes
+}
+
+func (c *Config) SetInterval(interval int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.Faucet.Minutes = interval
 }
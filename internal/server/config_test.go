@@ -0,0 +1,177 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigLoadFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr string
+	}{
+		{
+			name: "yaml",
+			file: "faucet.yaml",
+			content: `
+httpport: 9090
+faucet:
+  amount: 2
+  classes:
+    - name: internal
+      minutes: 60
+      addresses: ["0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B"]
+wallet:
+  provider: http://localhost:8545
+chains:
+  dusk: 912559
+`,
+		},
+		{
+			name: "toml",
+			file: "faucet.toml",
+			content: `
+httpport = 9090
+
+[faucet]
+amount = 2
+
+[[faucet.classes]]
+name = "internal"
+minutes = 60
+addresses = ["0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B"]
+
+[wallet]
+provider = "http://localhost:8545"
+
+[chains]
+dusk = 912559
+`,
+		},
+		{name: "unknown yaml key", file: "faucet.yaml", content: "faucet:\n  amout: 2\n", wantErr: "amout"},
+		{name: "unknown toml key", file: "faucet.toml", content: "[faucet]\namout = 2\n", wantErr: "amout"},
+		{name: "unsupported format", file: "faucet.json", content: "{}", wantErr: "unsupported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			cfg := DefaultConfig()
+			err := cfg.LoadFile(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadFile() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadFile() error = %v", err)
+			}
+			if cfg.HTTPPort != 9090 || cfg.Payout() != 2 || cfg.Interval() != 1440 || cfg.Wallet.Provider != "http://localhost:8545" {
+				t.Errorf("LoadFile() = %+v, want file values over defaults", cfg)
+			}
+			if len(cfg.Faucet.Classes) != 1 || cfg.Faucet.Classes[0].Interval != 60 {
+				t.Errorf("LoadFile() classes = %+v", cfg.Faucet.Classes)
+			}
+			if cfg.Chains["dusk"] != 912559 || cfg.Chains["sepolia"] != 11155111 {
+				t.Errorf("LoadFile() chains = %v, want file chains added to the defaults", cfg.Chains)
+			}
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(cfg *Config)
+		wantErr string
+	}{
+		{name: "valid", modify: func(cfg *Config) {}},
+		{name: "zero payout", modify: func(cfg *Config) { cfg.Faucet.Amount = 0 }, wantErr: "faucet.amount"},
+		{name: "missing provider", modify: func(cfg *Config) { cfg.Wallet.Provider = "" }, wantErr: "wallet.provider"},
+		{name: "missing key", modify: func(cfg *Config) { cfg.Wallet.PrivKey = "" }, wantErr: "wallet.privkey"},
+		{name: "admin without token", modify: func(cfg *Config) { cfg.Admin.Port = 8081 }, wantErr: "admin.token"},
+		{name: "invalid port", modify: func(cfg *Config) { cfg.GRPC.Port = 70000 }, wantErr: "grpc.port"},
+		{name: "unknown redaction", modify: func(cfg *Config) { cfg.Feed.Redact = "some" }, wantErr: "feed.redact"},
+		{name: "duplicate class", modify: func(cfg *Config) {
+			cfg.Faucet.Classes = []ClaimClass{{Name: "internal"}, {Name: "Internal"}}
+		}, wantErr: "faucet.classes"},
+		{name: "invalid threshold", modify: func(cfg *Config) { cfg.Monitor.Thresholds = "10,-1" }, wantErr: "monitor.thresholds"},
+		{name: "too precise floor", modify: func(cfg *Config) { cfg.Monitor.Floor = 1e-19 }, wantErr: "monitor.floor"},
+		{name: "invalid webhook", modify: func(cfg *Config) { cfg.Monitor.Webhooks = "hooks.example.com" }, wantErr: "monitor.webhooks"},
+		{name: "invalid tracing endpoint", modify: func(cfg *Config) { cfg.Tracing.Endpoint = "localhost:4318" }, wantErr: "tracing.endpoint"},
+		{name: "invalid tracing ratio", modify: func(cfg *Config) { cfg.Tracing.Ratio = 2 }, wantErr: "tracing.ratio"},
+		{name: "audit log without salt", modify: func(cfg *Config) { cfg.Audit.File = "audit.jsonl" }, wantErr: "audit.salt"},
+		{name: "unknown log format", modify: func(cfg *Config) { cfg.Log.Format = "logfmt" }, wantErr: "log.format"},
+		{name: "certificate without key", modify: func(cfg *Config) { cfg.TLS.CertFile = "cert.pem" }, wantErr: "tls.keyfile"},
+		{name: "certificate and ACME", modify: func(cfg *Config) {
+			cfg.TLS.CertFile, cfg.TLS.KeyFile, cfg.TLS.Domains = "cert.pem", "key.pem", "faucet.example.com"
+		}, wantErr: "tls.domains"},
+		{name: "invalid ACME directory", modify: func(cfg *Config) { cfg.TLS.Domains, cfg.TLS.Directory = "faucet.example.com", "localhost:14000" }, wantErr: "tls.directory"},
+		{name: "redirect without TLS", modify: func(cfg *Config) { cfg.TLS.RedirectPort = 80 }, wantErr: "tls.redirectport"},
+		{name: "admin socket without token", modify: func(cfg *Config) { cfg.Admin.Addr = "unix:/run/faucet-admin.sock" }, wantErr: "admin.token"},
+		{name: "shared socket", modify: func(cfg *Config) { cfg.HTTPAddr, cfg.Metrics.Addr = "unix:/run/faucet.sock", "unix:/run/faucet.sock" }, wantErr: "more than one listener"},
+		{name: "negative timeout", modify: func(cfg *Config) { cfg.HTTP.WriteTimeout = -1 }, wantErr: "http timeouts"},
+		{name: "unknown log level", modify: func(cfg *Config) { cfg.Log.Level = "verbose" }, wantErr: "log.level"},
+		{name: "network", modify: func(cfg *Config) {
+			cfg.Networks = []NetworkConfig{{Name: "dusk", Wallet: WalletConfig{Provider: "http://localhost:8546"}}}
+		}},
+		{name: "network without provider", modify: func(cfg *Config) {
+			cfg.Networks = []NetworkConfig{{Name: "dusk"}}
+		}, wantErr: "networks.dusk: wallet.provider"},
+		{name: "reserved network name", modify: func(cfg *Config) {
+			cfg.Networks = []NetworkConfig{{Name: "claim", Wallet: WalletConfig{Provider: "http://localhost:8546"}}}
+		}, wantErr: "reserved"},
+		{name: "invalid network name", modify: func(cfg *Config) {
+			cfg.Networks = []NetworkConfig{{Name: "Dusk 1", Wallet: WalletConfig{Provider: "http://localhost:8546"}}}
+		}, wantErr: "lowercase"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Wallet.Provider = "http://localhost:8545"
+			cfg.Wallet.PrivKey = "0x01"
+			tt.modify(cfg)
+			err := cfg.Validate()
+			if (err != nil) != (tt.wantErr != "") || (err != nil && !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigForNetwork(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.HTTPPort = 9090
+	cfg.QueueFile = "queue.json"
+	cfg.Faucet.Amount = 2
+	cfg.Wallet.PrivKey = "0x01"
+	cfg.Wallet.Provider = "http://localhost:8545"
+	cfg.Wallet.ChainID = 5
+
+	sub := cfg.ForNetwork(NetworkConfig{
+		Name:   "sepolia",
+		Faucet: FaucetConfig{Minutes: 60},
+		Wallet: WalletConfig{Provider: "http://localhost:8546"},
+	})
+	if sub.HTTPPort != 0 || sub.QueueFile != "" {
+		t.Errorf("ForNetwork() = %d %q, want no listener and queue file", sub.HTTPPort, sub.QueueFile)
+	}
+	if sub.Network() != "sepolia" || sub.Payout() != 2 || sub.Interval() != 60 {
+		t.Errorf("ForNetwork() faucet = %+v, want inherited payout", sub.Faucet)
+	}
+	if sub.Wallet.PrivKey != "0x01" || sub.Wallet.Provider != "http://localhost:8546" || sub.ChainID() != 11155111 {
+		t.Errorf("ForNetwork() wallet = %+v, want own provider and chain ID", sub.Wallet)
+	}
+
+	sub = cfg.ForNetwork(NetworkConfig{Name: "dusk", Wallet: WalletConfig{KeyJSON: "dusk.json"}})
+	if sub.Wallet.PrivKey != "" || sub.Wallet.KeyJSON != "dusk.json" || sub.ChainID() != 0 {
+		t.Errorf("ForNetwork() wallet = %+v, want own signer", sub.Wallet)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/chainflag/eth-faucet/internal/chain"
 )
@@ -17,7 +18,47 @@ type claimRequest struct {
 }
 
 type claimResponse struct {
-	Message string `json:"msg"`
+	Message string    `json:"msg"`
+	Code    ErrorCode `json:"code,omitempty"`
+	ID      string    `json:"id,omitempty"`
+	// RequestID is only set on errors, to be quoted in support requests
+	RequestID string `json:"requestId,omitempty"`
+}
+
+type claimStatusResponse struct {
+	ID          string    `json:"id"`
+	Status      string    `json:"status"`
+	TxHash      string    `json:"txHash,omitempty"`
+	BlockNumber uint64    `json:"blockNumber,omitempty"`
+	Queued      bool      `json:"queued"`
+	Position    int       `json:"position,omitempty"`
+	Code        ErrorCode `json:"code,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+type batchClaimRequest struct {
+	Claims []batchClaimEntry `json:"claims"`
+}
+
+type batchClaimEntry struct {
+	Address string `json:"address"`
+	Amount  string `json:"amount"`
+}
+
+type batchClaimResponse struct {
+	Message string             `json:"msg"`
+	Code    ErrorCode          `json:"code,omitempty"`
+	BatchID string             `json:"batchId,omitempty"`
+	Claims  []batchClaimStatus `json:"claims"`
+}
+
+type batchClaimStatus struct {
+	ID      string    `json:"id,omitempty"`
+	Address string    `json:"address"`
+	Amount  string    `json:"amount,omitempty"`
+	Status  string    `json:"status"`
+	Code    ErrorCode `json:"code,omitempty"`
+	Error   string    `json:"error,omitempty"`
 }
 
 type infoResponse struct {
@@ -26,8 +67,32 @@ type infoResponse struct {
 	Payout  string `json:"payout"`
 }
 
+// networkResponse lists a network and the path prefix of its API. The
+// default network has an empty ID.
+type networkResponse struct {
+	ID      string `json:"id"`
+	Network string `json:"network"`
+	Path    string `json:"path"`
+}
+
+// statsResponse sums up the claims sent by a network. Amounts are in Wei.
+type statsResponse struct {
+	Network         string        `json:"network"`
+	TotalClaims     int           `json:"totalClaims"`
+	TotalAmount     string        `json:"totalAmount"`
+	UniqueAddresses int           `json:"uniqueAddresses"`
+	Daily           []statsBucket `json:"daily"`
+	Hourly          []statsBucket `json:"hourly"`
+}
+
+type statsBucket struct {
+	Time   time.Time `json:"time"`
+	Claims int       `json:"claims"`
+	Amount string    `json:"amount"`
+}
+
 type malformedRequest struct {
-	status  int
+	code    ErrorCode
 	message string
 }
 
@@ -36,10 +101,21 @@ func (mr *malformedRequest) Error() string {
 }
 
 func decodeJSONBody(r *http.Request, dst interface{}) error {
-	body, err := io.ReadAll(io.LimitReader(r.Body, 1024))
+	return decodeJSONBodyLimit(r, dst, 1024)
+}
+
+// decodeJSONBodyLimit decodes a request body of at most limit bytes. One byte
+// more is read, so that a larger body is reported as such instead of as
+// malformed JSON.
+func decodeJSONBodyLimit(r *http.Request, dst interface{}, limit int64) error {
+	body, err := io.ReadAll(io.LimitReader(r.Body, limit+1))
 	defer r.Body.Close()
 	if err != nil {
-		return &malformedRequest{status: http.StatusBadRequest, message: "Unable to read request body"}
+		return &malformedRequest{code: ErrInvalidRequest, message: "Unable to read request body"}
+	}
+	if int64(len(body)) > limit {
+		msg := fmt.Sprintf("Request body must not be larger than %s", formatBytes(limit))
+		return &malformedRequest{code: ErrPayloadTooLarge, message: msg}
 	}
 
 	dec := json.NewDecoder(bytes.NewReader(body))
@@ -52,23 +128,20 @@ func decodeJSONBody(r *http.Request, dst interface{}) error {
 		switch {
 		case errors.As(err, &syntaxError):
 			msg := fmt.Sprintf("Request body contains badly-formed JSON (at position %d)", syntaxError.Offset)
-			return &malformedRequest{status: http.StatusBadRequest, message: msg}
+			return &malformedRequest{code: ErrInvalidRequest, message: msg}
 		case errors.Is(err, io.ErrUnexpectedEOF):
 			msg := fmt.Sprintf("Request body contains badly-formed JSON")
-			return &malformedRequest{status: http.StatusBadRequest, message: msg}
+			return &malformedRequest{code: ErrInvalidRequest, message: msg}
 		case errors.As(err, &unmarshalTypeError):
 			msg := fmt.Sprintf("Request body contains an invalid value for the %q field (at position %d)", unmarshalTypeError.Field, unmarshalTypeError.Offset)
-			return &malformedRequest{status: http.StatusBadRequest, message: msg}
+			return &malformedRequest{code: ErrInvalidRequest, message: msg}
 		case strings.HasPrefix(err.Error(), "json: unknown field "):
 			fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
 			msg := fmt.Sprintf("Request body contains unknown field %s", fieldName)
-			return &malformedRequest{status: http.StatusBadRequest, message: msg}
+			return &malformedRequest{code: ErrInvalidRequest, message: msg}
 		case errors.Is(err, io.EOF):
 			msg := "Request body must not be empty"
-			return &malformedRequest{status: http.StatusBadRequest, message: msg}
-		case err.Error() == "http: request body too large":
-			msg := "Request body must not be larger than 1MB"
-			return &malformedRequest{status: http.StatusRequestEntityTooLarge, message: msg}
+			return &malformedRequest{code: ErrInvalidRequest, message: msg}
 		default:
 			return err
 		}
@@ -78,18 +151,35 @@ func decodeJSONBody(r *http.Request, dst interface{}) error {
 	return nil
 }
 
+// formatBytes formats a size in bytes, e.g. 64KB for 65536.
+func formatBytes(size int64) string {
+	if size >= 1024 && size%1024 == 0 {
+		return fmt.Sprintf("%dKB", size/1024)
+	}
+	return fmt.Sprintf("%d bytes", size)
+}
+
 func readAddress(r *http.Request) (string, error) {
 	var claimReq claimRequest
 	if err := decodeJSONBody(r, &claimReq); err != nil {
 		return "", err
 	}
 	if !chain.IsValidAddress(claimReq.Address, true) {
-		return "", &malformedRequest{status: http.StatusBadRequest, message: "invalid address"}
+		return "", &malformedRequest{code: ErrInvalidAddress, message: "invalid address"}
 	}
 
 	return claimReq.Address, nil
 }
 
+func renderReadError(w http.ResponseWriter, r *http.Request, err error) {
+	var mr *malformedRequest
+	if errors.As(err, &mr) {
+		renderError(w, r, &apiError{Code: mr.code, Message: mr.message})
+	} else {
+		renderError(w, r, internalError())
+	}
+}
+
 func renderJSON(w http.ResponseWriter, v interface{}, code int) error {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ErrorCode is a stable, machine-readable identifier of a failure.
+type ErrorCode string
+
+const (
+	ErrInvalidRequest      ErrorCode = "INVALID_REQUEST"
+	ErrInvalidAddress      ErrorCode = "INVALID_ADDRESS"
+	ErrInvalidAmount       ErrorCode = "INVALID_AMOUNT"
+	ErrInvalidBatch        ErrorCode = "INVALID_BATCH"
+	ErrPayloadTooLarge     ErrorCode = "PAYLOAD_TOO_LARGE"
+	ErrUnauthorized        ErrorCode = "UNAUTHORIZED"
+	ErrInvalidAPIKey       ErrorCode = "INVALID_API_KEY"
+	ErrAPIKeyExpired       ErrorCode = "API_KEY_EXPIRED"
+	ErrTokenNotAllowed     ErrorCode = "TOKEN_NOT_ALLOWED"
+	ErrNotFound            ErrorCode = "NOT_FOUND"
+	ErrRateLimited         ErrorCode = "RATE_LIMITED"
+	ErrQuotaExceeded       ErrorCode = "QUOTA_EXCEEDED"
+	ErrQueueFull           ErrorCode = "QUEUE_FULL"
+	ErrFaucetPaused        ErrorCode = "FAUCET_PAUSED"
+	ErrShuttingDown        ErrorCode = "SHUTTING_DOWN"
+	ErrFaucetEmpty         ErrorCode = "FAUCET_EMPTY"
+	ErrUpstreamUnavailable ErrorCode = "UPSTREAM_UNAVAILABLE"
+	ErrTransferFailed      ErrorCode = "TRANSFER_FAILED"
+	ErrTransactionReverted ErrorCode = "TRANSACTION_REVERTED"
+	ErrInternal            ErrorCode = "INTERNAL_ERROR"
+)
+
+var errorStatus = map[ErrorCode]int{
+	ErrInvalidRequest:      http.StatusBadRequest,
+	ErrInvalidAddress:      http.StatusBadRequest,
+	ErrInvalidAmount:       http.StatusBadRequest,
+	ErrInvalidBatch:        http.StatusBadRequest,
+	ErrPayloadTooLarge:     http.StatusRequestEntityTooLarge,
+	ErrUnauthorized:        http.StatusUnauthorized,
+	ErrInvalidAPIKey:       http.StatusUnauthorized,
+	ErrAPIKeyExpired:       http.StatusUnauthorized,
+	ErrTokenNotAllowed:     http.StatusForbidden,
+	ErrNotFound:            http.StatusNotFound,
+	ErrRateLimited:         http.StatusTooManyRequests,
+	ErrQuotaExceeded:       http.StatusTooManyRequests,
+	ErrQueueFull:           http.StatusServiceUnavailable,
+	ErrFaucetPaused:        http.StatusServiceUnavailable,
+	ErrShuttingDown:        http.StatusServiceUnavailable,
+	ErrFaucetEmpty:         http.StatusServiceUnavailable,
+	ErrUpstreamUnavailable: http.StatusBadGateway,
+	ErrTransferFailed:      http.StatusInternalServerError,
+	ErrTransactionReverted: http.StatusInternalServerError,
+	ErrInternal:            http.StatusInternalServerError,
+}
+
+// HTTPStatus returns the HTTP status that the code is always rendered with.
+func (c ErrorCode) HTTPStatus() int {
+	if status, ok := errorStatus[c]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+const retryAfterUnavailable = 30 * time.Second
+
+// apiError is a failure that is rendered to the client. Its message never
+// contains internal details such as raw RPC errors.
+type apiError struct {
+	Code       ErrorCode
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *apiError) Error() string {
+	return e.Message
+}
+
+func newAPIError(code ErrorCode, format string, args ...interface{}) *apiError {
+	return &apiError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func rateLimited(code ErrorCode, wait time.Duration, format string, args ...interface{}) *apiError {
+	return &apiError{Code: code, Message: fmt.Sprintf(format, args...), RetryAfter: wait}
+}
+
+func internalError() *apiError {
+	return newAPIError(ErrInternal, http.StatusText(http.StatusInternalServerError))
+}
+
+var (
+	errQueueFull    = &apiError{Code: ErrQueueFull, Message: "Faucet queue is too long, please try again later", RetryAfter: retryAfterUnavailable}
+	errPaused       = &apiError{Code: ErrFaucetPaused, Message: "Faucet is paused, please try again later"}
+	errLowBalance   = &apiError{Code: ErrFaucetEmpty, Message: "Faucet is running low on funds and paused until it is refilled, please try again later", RetryAfter: retryAfterUnavailable}
+	errShuttingDown = &apiError{Code: ErrShuttingDown, Message: "Faucet is shutting down, please try again later", RetryAfter: retryAfterUnavailable}
+	errClaimMissing = &apiError{Code: ErrNotFound, Message: "Claim not found"}
+)
+
+// transferError sanitizes a failed transfer into an error that is safe to show.
+func transferError(err error) *apiError {
+	var netErr net.Error
+	var httpErr rpc.HTTPError
+	switch {
+	case err == nil:
+		return nil
+	case strings.Contains(strings.ToLower(err.Error()), "insufficient funds"):
+		return &apiError{Code: ErrFaucetEmpty, Message: "Faucet is out of funds, please try again later"}
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled),
+		errors.As(err, &netErr), errors.As(err, &httpErr):
+		return &apiError{Code: ErrUpstreamUnavailable, Message: "Network node is unavailable, please try again later", RetryAfter: retryAfterUnavailable}
+	default:
+		return &apiError{Code: ErrTransferFailed, Message: "Failed to send transaction, please try again later"}
+	}
+}
+
+type errorResponse struct {
+	Code       ErrorCode `json:"code"`
+	Message    string    `json:"message"`
+	RetryAfter int       `json:"retryAfter,omitempty"`
+	RequestID  string    `json:"requestId,omitempty"`
+}
+
+func isV1(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/api/v1/")
+}
+
+func renderError(w http.ResponseWriter, r *http.Request, err *apiError) {
+	retryAfter := ceilSeconds(err.RetryAfter)
+	if retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	}
+	if !isV1(r) {
+		renderJSON(w, claimResponse{Message: err.Message, Code: err.Code, RequestID: requestIDFromContext(r.Context())}, err.Code.HTTPStatus())
+		return
+	}
+	renderJSON(w, errorResponse{
+		Code:       err.Code,
+		Message:    err.Message,
+		RetryAfter: retryAfter,
+		RequestID:  requestIDFromContext(r.Context()),
+	}, err.Code.HTTPStatus())
+}
+
+// setRateLimitHeaders sets the RateLimit header fields of the IETF draft.
+func setRateLimitHeaders(w http.ResponseWriter, limit, remaining int, reset time.Duration) {
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(reset)))
+}
+
+// ceilSeconds rounds a duration up to whole seconds, so that a limit that is
+// still active is never reported as resetting in 0 seconds.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func TestTransferError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code ErrorCode
+	}{
+		{name: "insufficient funds", err: errors.New("insufficient funds for gas * price + value"), code: ErrFaucetEmpty},
+		{name: "timeout", err: fmt.Errorf("post: %w", context.DeadlineExceeded), code: ErrUpstreamUnavailable},
+		{name: "node error", err: rpc.HTTPError{StatusCode: http.StatusBadGateway, Status: "502 Bad Gateway"}, code: ErrUpstreamUnavailable},
+		{name: "nonce too low", err: errors.New("nonce too low: address 0xAb58, tx: 1 state: 2"), code: ErrTransferFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := transferError(tt.err)
+			if got.Code != tt.code {
+				t.Errorf("transferError() code = %s, want %s", got.Code, tt.code)
+			}
+			if got.Message == tt.err.Error() {
+				t.Errorf("transferError() leaks %q", got.Message)
+			}
+		})
+	}
+}
+
+func TestRenderError(t *testing.T) {
+	for _, path := range []string{"/api/claim", "/api/v1/claim"} {
+		rec := httptest.NewRecorder()
+		renderError(rec, httptest.NewRequest("POST", path, nil), errQueueFull)
+		if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "30" {
+			t.Errorf("%s: status = %d, Retry-After = %q", path, rec.Code, rec.Header().Get("Retry-After"))
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/chainflag/eth-faucet/internal/chain"
+)
+
+const (
+	confirmationDepth = 3
+	watchInterval     = 3 * time.Second
+	watchWindow       = time.Hour
+	keepAliveInterval = 15 * time.Second
+)
+
+// watchClaims follows broadcast claims until they are confirmed.
+func (s *Server) watchClaims() {
+	defer s.workers.Done()
+	watcher, ok := s.TxBuilder.(chain.TxWatcher)
+	if !ok {
+		return
+	}
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.checkClaims(watcher)
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+func (s *Server) checkClaims(watcher chain.TxWatcher) {
+	for _, record := range s.tracker.pending(time.Now().Add(-watchWindow)) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		status, err := watcher.TransactionStatus(ctx, common.HexToHash(record.TxHash))
+		cancel()
+		if errors.Is(err, ethereum.NotFound) {
+			continue
+		} else if err != nil {
+			log.WithError(err).WithField("txHash", record.TxHash).Debug("Failed to look up transaction status")
+			continue
+		}
+
+		switch {
+		case !status.Successful:
+			s.tracker.reportBlock(record.ID, statusFailed, status.BlockNumber, newAPIError(ErrTransactionReverted, "Transaction reverted"))
+		case status.Confirmations >= confirmationDepth:
+			s.tracker.reportBlock(record.ID, statusConfirmed, status.BlockNumber, nil)
+		default:
+			s.tracker.reportBlock(record.ID, statusMined, status.BlockNumber, nil)
+		}
+	}
+}
+
+// handleClaimByID serves the status of a claim on /api/v1/claim/{id} and streams
+// its progress as server-sent events on /api/v1/claim/{id}/events. The legacy
+// /api/claim/ prefix is served as well.
+func (s *Server) handleClaimByID() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			http.NotFound(w, r)
+			return
+		}
+		id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/api/v1/claim/"), "/api/claim/")
+		if strings.HasSuffix(id, "/events") {
+			s.streamClaimEvents(w, r, strings.TrimSuffix(id, "/events"))
+			return
+		}
+
+		record, ok := s.tracker.get(id)
+		if !ok || strings.Contains(id, "/") {
+			renderError(w, r, errClaimMissing)
+			return
+		}
+		renderJSON(w, s.claimStatus(record), http.StatusOK)
+	}
+}
+
+func (s *Server) streamClaimEvents(w http.ResponseWriter, r *http.Request, id string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		renderError(w, r, newAPIError(ErrInternal, "Streaming is not supported"))
+		return
+	}
+
+	// Subscribe before reading the record so that no change is missed in between
+	updates, unsubscribe := s.tracker.subscribe(id)
+	defer unsubscribe()
+	record, ok := s.tracker.get(id)
+	if !ok {
+		renderError(w, r, errClaimMissing)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	extendWriteDeadline(r.Context())
+	if err := writeEvent(w, record); err != nil || record.done() {
+		flusher.Flush()
+		return
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case record = <-updates:
+			extendWriteDeadline(r.Context())
+			if err := writeEvent(w, record); err != nil {
+				return
+			}
+			flusher.Flush()
+			if record.done() {
+				return
+			}
+		case <-keepAlive.C:
+			extendWriteDeadline(r.Context())
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-s.closing:
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, record claimRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+	return err
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chainflag/eth-faucet/internal/chain"
+)
+
+func TestHandleClaimEvents(t *testing.T) {
+	s := newTestServer(t, &mockTxBuilder{}, testConfig())
+	item := &queueItem{ID: "claim", Address: "0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B", Class: publicClassName, Amount: chain.EtherToWei(1)}
+	s.tracker.report(item, statusQueued, "", nil)
+	ts := httptest.NewServer(s.handleClaimByID())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/claim/missing/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown claim status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+
+	resp, err = http.Get(ts.URL + "/api/claim/claim/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	want := []string{statusQueued, statusBroadcast, statusMined, statusConfirmed}
+	scanner := bufio.NewScanner(resp.Body)
+	for i := 0; i < len(want); {
+		if !scanner.Scan() {
+			t.Fatalf("stream ended after %d events: %v", i, scanner.Err())
+		}
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var record claimRecord
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &record); err != nil {
+			t.Fatal(err)
+		}
+		if record.Status != want[i] {
+			t.Fatalf("event %d status = %s, want %s", i, record.Status, want[i])
+		}
+		i++
+		// Advance the claim only after the previous state was received
+		switch i {
+		case 1:
+			s.tracker.report(item, statusBroadcast, "0x01", nil)
+		case 2:
+			s.tracker.reportBlock(item.ID, statusMined, 7, nil)
+		case 3:
+			s.tracker.reportBlock(item.ID, statusConfirmed, 7, nil)
+		}
+	}
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			t.Errorf("stream did not end after the claim was confirmed: %q", line)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/chainflag/eth-faucet/internal/chain"
+)
+
+const (
+	RedactNone    = "none"
+	RedactPartial = "partial"
+	RedactFull    = "full"
+
+	feedBufferSize      = 32
+	feedWriteTimeout    = 10 * time.Second
+	feedPingInterval    = 30 * time.Second
+	feedBalanceInterval = 15 * time.Second
+)
+
+type feedEvent struct {
+	Type    string     `json:"type"`
+	Claim   *feedClaim `json:"claim,omitempty"`
+	Balance string     `json:"balance,omitempty"`
+	Queued  *int       `json:"queued,omitempty"`
+}
+
+type feedClaim struct {
+	ID          string `json:"id"`
+	Address     string `json:"address,omitempty"`
+	Amount      string `json:"amount"`
+	Class       string `json:"class"`
+	Status      string `json:"status"`
+	TxHash      string `json:"txHash,omitempty"`
+	BlockNumber uint64 `json:"blockNumber,omitempty"`
+}
+
+type feedClient struct {
+	conn *websocket.Conn
+	send chan feedEvent
+}
+
+// activityFeed broadcasts anonymized faucet activity to WebSocket subscribers.
+// Clients that cannot keep up with the feed are disconnected instead of
+// holding back the other subscribers.
+type activityFeed struct {
+	mutex     sync.Mutex
+	clients   map[*feedClient]bool
+	redaction string
+	balance   string
+	queued    int
+	refKey    []byte
+	upgrader  websocket.Upgrader
+}
+
+func newActivityFeed(redaction string) *activityFeed {
+	refKey := make([]byte, 32)
+	if _, err := rand.Read(refKey); err != nil {
+		panic(err)
+	}
+	return &activityFeed{
+		clients:   make(map[*feedClient]bool),
+		redaction: redaction,
+		refKey:    refKey,
+		upgrader: websocket.Upgrader{
+			// Any origin may subscribe, since the feed is meant to be embedded in
+			// dashboards on other sites. This is safe as the feed is read-only and
+			// sends the same redacted events to everyone, whatever the cookies or
+			// credentials of the browser.
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+// claimRef returns the ID under which a claim is published. Unless addresses
+// are shown in full, it is derived from the claim ID with a key of this
+// process, so that subscribers can follow a claim but cannot look up its
+// unredacted status.
+func (f *activityFeed) claimRef(id string) string {
+	if f.redaction == RedactNone {
+		return id
+	}
+	mac := hmac.New(sha256.New, f.refKey)
+	mac.Write([]byte(id))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+func redactAddress(address, redaction string) string {
+	switch redaction {
+	case RedactNone:
+		return address
+	case RedactFull:
+		return ""
+	default:
+		if len(address) < 10 {
+			return address
+		}
+		return address[:6] + "…" + address[len(address)-4:]
+	}
+}
+
+// redactTxHash returns the tx hash of a claim if addresses are shown in full.
+// The hash leads to the recipient on any block explorer, so it is omitted
+// whenever the address is redacted.
+func redactTxHash(txHash, redaction string) string {
+	if redaction != RedactNone {
+		return ""
+	}
+	return txHash
+}
+
+func (f *activityFeed) publishClaim(record claimRecord) {
+	claim := &feedClaim{
+		ID:          f.claimRef(record.ID),
+		Address:     redactAddress(record.Address, f.redaction),
+		Amount:      record.Amount,
+		Class:       record.Class,
+		Status:      record.Status,
+		TxHash:      redactTxHash(record.TxHash, f.redaction),
+		BlockNumber: record.BlockNumber,
+	}
+	f.broadcast(feedEvent{Type: "claim", Claim: claim})
+}
+
+func (f *activityFeed) publishBalance(balance *big.Int) {
+	f.mutex.Lock()
+	changed := balance.String() != f.balance
+	f.balance = balance.String()
+	f.mutex.Unlock()
+	if changed {
+		f.broadcast(feedEvent{Type: "balance", Balance: balance.String()})
+	}
+}
+
+func (f *activityFeed) publishQueue(queued int) {
+	f.mutex.Lock()
+	changed := queued != f.queued
+	f.queued = queued
+	f.mutex.Unlock()
+	if changed {
+		f.broadcast(feedEvent{Type: "queue", Queued: &queued})
+	}
+}
+
+func (f *activityFeed) broadcast(event feedEvent) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for client := range f.clients {
+		select {
+		case client.send <- event:
+		default:
+			log.WithField("remoteAddr", client.conn.RemoteAddr()).Warn("Disconnecting slow feed subscriber")
+			f.remove(client)
+		}
+	}
+}
+
+// remove closes the send channel of the client. The caller must hold f.mutex.
+func (f *activityFeed) remove(client *feedClient) {
+	if f.clients[client] {
+		delete(f.clients, client)
+		close(client.send)
+	}
+}
+
+func (f *activityFeed) close() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for client := range f.clients {
+		f.remove(client)
+	}
+}
+
+func (f *activityFeed) subscribers() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return len(f.clients)
+}
+
+func (f *activityFeed) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := f.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	client := &feedClient{conn: conn, send: make(chan feedEvent, feedBufferSize)}
+
+	f.mutex.Lock()
+	f.clients[client] = true
+	queued := f.queued
+	client.send <- feedEvent{Type: "queue", Queued: &queued}
+	if f.balance != "" {
+		client.send <- feedEvent{Type: "balance", Balance: f.balance}
+	}
+	f.mutex.Unlock()
+
+	go f.writePump(client)
+	f.readPump(client)
+}
+
+// readPump discards incoming messages and unsubscribes the client once the
+// connection is closed.
+func (f *activityFeed) readPump(client *feedClient) {
+	defer func() {
+		f.mutex.Lock()
+		f.remove(client)
+		f.mutex.Unlock()
+	}()
+	client.conn.SetReadLimit(512)
+	client.conn.SetReadDeadline(time.Now().Add(feedPingInterval * 2))
+	client.conn.SetPongHandler(func(string) error {
+		return client.conn.SetReadDeadline(time.Now().Add(feedPingInterval * 2))
+	})
+	for {
+		if _, _, err := client.conn.NextReader(); err != nil {
+			return
+		}
+	}
+}
+
+func (f *activityFeed) writePump(client *feedClient) {
+	ticker := time.NewTicker(feedPingInterval)
+	defer func() {
+		ticker.Stop()
+		client.conn.Close()
+	}()
+	for {
+		select {
+		case event, ok := <-client.send:
+			client.conn.SetWriteDeadline(time.Now().Add(feedWriteTimeout))
+			if !ok {
+				client.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+				return
+			}
+			if err := client.conn.WriteJSON(event); err != nil {
+				return
+			}
+		case <-ticker.C:
+			client.conn.SetWriteDeadline(time.Now().Add(feedWriteTimeout))
+			if err := client.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// runFeed publishes the queue depth and faucet balance whenever they change.
+func (s *Server) runFeed() {
+	defer s.workers.Done()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	var lastBalance time.Time
+	for {
+		select {
+		case <-ticker.C:
+			s.feed.publishQueue(s.queue.len())
+			reader, ok := s.TxBuilder.(chain.BalanceReader)
+			if !ok || time.Since(lastBalance) < feedBalanceInterval {
+				continue
+			}
+			lastBalance = time.Now()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			balance, err := reader.Balance(ctx)
+			cancel()
+			if err != nil {
+				log.WithError(err).Debug("Failed to look up faucet balance")
+				continue
+			}
+			s.feed.publishBalance(balance)
+		case <-s.quit:
+			s.feed.close()
+			return
+		}
+	}
+}
+
+func ParseRedaction(redaction string) (string, error) {
+	switch strings.ToLower(redaction) {
+	case RedactNone, RedactPartial, RedactFull:
+		return strings.ToLower(redaction), nil
+	default:
+		return "", fmt.Errorf("unknown redaction level %q", redaction)
+	}
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/chainflag/eth-faucet/internal/chain"
+)
+
+func TestRedactAddress(t *testing.T) {
+	address := "0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B"
+	tests := []struct {
+		redaction string
+		want      string
+	}{
+		{redaction: RedactNone, want: address},
+		{redaction: RedactPartial, want: "0xAb58…eC9B"},
+		{redaction: RedactFull, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.redaction, func(t *testing.T) {
+			if got := redactAddress(address, tt.redaction); got != tt.want {
+				t.Errorf("redactAddress() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactTxHash(t *testing.T) {
+	for redaction, want := range map[string]string{RedactNone: "0x01", RedactPartial: "", RedactFull: ""} {
+		if got := redactTxHash("0x01", redaction); got != want {
+			t.Errorf("redactTxHash() = %q under %s redaction, want %q", got, redaction, want)
+		}
+	}
+}
+
+func TestActivityFeed(t *testing.T) {
+	feed := newActivityFeed(RedactFull)
+	ts := httptest.NewServer(feed)
+	defer ts.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var event feedEvent
+	if err := conn.ReadJSON(&event); err != nil || event.Type != "queue" {
+		t.Fatalf("first event = %+v, %v, want queue snapshot", event, err)
+	}
+
+	feed.publishClaim(claimRecord{ID: "claim", Address: "0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B", Status: statusBroadcast, TxHash: "0x01"})
+	event = feedEvent{}
+	if err := conn.ReadJSON(&event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != "claim" || event.Claim == nil || event.Claim.ID == "" {
+		t.Fatalf("claim event = %+v", event)
+	}
+	if event.Claim.Address != "" || event.Claim.TxHash != "" {
+		t.Errorf("claim event was not redacted: %+v", event.Claim)
+	}
+}
+
+func TestActivityFeedSlowClient(t *testing.T) {
+	feed := newActivityFeed(RedactPartial)
+	ts := httptest.NewServer(feed)
+	defer ts.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for feed.subscribers() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Without reading, the client falls behind and is dropped from the feed
+	for i := 0; i < feedBufferSize*1000 && feed.subscribers() > 0; i++ {
+		feed.publishQueue(i + 1)
+	}
+	if feed.subscribers() != 0 {
+		t.Errorf("slow client was not disconnected")
+	}
+}
+
+func TestActivityFeedClaimRef(t *testing.T) {
+	s := newTestServer(t, &mockTxBuilder{}, testConfig())
+	ts := httptest.NewServer(s.setupRouter())
+	defer ts.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/api/v1/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var event feedEvent
+	if err := conn.ReadJSON(&event); err != nil {
+		t.Fatal(err)
+	}
+
+	item := &queueItem{ID: "claim", Address: "0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B", Class: publicClassName, Amount: chain.EtherToWei(1)}
+	s.tracker.report(item, statusConfirmed, "0x01", nil)
+	event = feedEvent{}
+	if err := conn.ReadJSON(&event); err != nil || event.Claim == nil {
+		t.Fatalf("claim event = %+v, %v", event, err)
+	}
+	if event.Claim.ID == item.ID {
+		t.Fatalf("feed published the claim ID")
+	}
+
+	// The published ID must not give access to the unredacted claim
+	for _, path := range []string{"/api/v1/claim/" + event.Claim.ID, "/api/v1/claim/" + event.Claim.ID + "/events"} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("GET %s = %d, want %d", path, resp.StatusCode, http.StatusNotFound)
+		}
+	}
+}
@@ -0,0 +1,393 @@
+package server
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	faucetv1 "github.com/chainflag/eth-faucet/api/faucet/v1"
+	"github.com/chainflag/eth-faucet/internal/chain"
+)
+
+const (
+	grpcClaimMethod = "/faucet.v1.FaucetService/Claim"
+	grpcErrorDomain = "eth-faucet"
+
+	maxStreamedClaims = 100
+)
+
+var grpcCodes = map[ErrorCode]codes.Code{
+	ErrInvalidRequest:      codes.InvalidArgument,
+	ErrInvalidAddress:      codes.InvalidArgument,
+	ErrInvalidAmount:       codes.InvalidArgument,
+	ErrInvalidBatch:        codes.InvalidArgument,
+	ErrPayloadTooLarge:     codes.InvalidArgument,
+	ErrUnauthorized:        codes.Unauthenticated,
+	ErrInvalidAPIKey:       codes.Unauthenticated,
+	ErrAPIKeyExpired:       codes.Unauthenticated,
+	ErrTokenNotAllowed:     codes.PermissionDenied,
+	ErrNotFound:            codes.NotFound,
+	ErrRateLimited:         codes.ResourceExhausted,
+	ErrQuotaExceeded:       codes.ResourceExhausted,
+	ErrQueueFull:           codes.ResourceExhausted,
+	ErrFaucetPaused:        codes.Unavailable,
+	ErrShuttingDown:        codes.Unavailable,
+	ErrFaucetEmpty:         codes.FailedPrecondition,
+	ErrUpstreamUnavailable: codes.Unavailable,
+	ErrTransferFailed:      codes.Internal,
+	ErrTransactionReverted: codes.Internal,
+	ErrInternal:            codes.Internal,
+}
+
+var claimStatuses = map[string]faucetv1.ClaimStatus{
+	statusQueued:    faucetv1.ClaimStatus_CLAIM_STATUS_QUEUED,
+	statusBroadcast: faucetv1.ClaimStatus_CLAIM_STATUS_BROADCAST,
+	statusMined:     faucetv1.ClaimStatus_CLAIM_STATUS_MINED,
+	statusConfirmed: faucetv1.ClaimStatus_CLAIM_STATUS_CONFIRMED,
+	statusFailed:    faucetv1.ClaimStatus_CLAIM_STATUS_FAILED,
+	statusCancelled: faucetv1.ClaimStatus_CLAIM_STATUS_CANCELLED,
+}
+
+// grpcError converts an API error into a gRPC status carrying its error code.
+func grpcError(err *apiError) error {
+	code, ok := grpcCodes[err.Code]
+	if !ok {
+		code = codes.Internal
+	}
+	st := status.New(code, err.Message)
+	info := &errdetails.ErrorInfo{Reason: string(err.Code), Domain: grpcErrorDomain}
+	withDetails, derr := st.WithDetails(info)
+	if err.RetryAfter > 0 {
+		withDetails, derr = st.WithDetails(info, &errdetails.RetryInfo{RetryDelay: durationpb.New(err.RetryAfter)})
+	}
+	if derr == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+type grpcService struct {
+	faucetv1.UnimplementedFaucetServiceServer
+	s *Server
+}
+
+func (s *Server) newGRPCServer() *grpc.Server {
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(requestIDUnaryInterceptor, s.authUnaryInterceptor, s.limitUnaryInterceptor),
+		grpc.ChainStreamInterceptor(requestIDStreamInterceptor, s.authStreamInterceptor),
+	}
+	if s.tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(s.tlsConfig)))
+	}
+	srv := grpc.NewServer(opts...)
+	faucetv1.RegisterFaucetServiceServer(srv, &grpcService{s: s})
+	return srv
+}
+
+// requestIDUnaryInterceptor assigns request IDs like the HTTP middleware. The
+// ID is read from and returned in the x-request-id metadata.
+func requestIDUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	id := grpcRequestID(ctx)
+	grpc.SetHeader(ctx, metadata.Pairs(requestIDHeader, id))
+	return handler(withRequestID(ctx, id), req)
+}
+
+func requestIDStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	id := grpcRequestID(ss.Context())
+	ss.SetHeader(metadata.Pairs(requestIDHeader, id))
+	return handler(srv, &contextStream{ServerStream: ss, ctx: withRequestID(ss.Context(), id)})
+}
+
+func grpcRequestID(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(requestIDHeader); len(values) > 0 && requestIDPattern.MatchString(values[0]) {
+		return values[0]
+	}
+	return uuid.NewString()
+}
+
+type adminContextKey struct{}
+
+func isAdminContext(ctx context.Context) bool {
+	admin, _ := ctx.Value(adminContextKey{}).(bool)
+	return admin
+}
+
+// authorizeContext resolves the API key or admin token of the authorization
+// metadata. Calls without it, or without a key store, continue anonymously.
+func (s *Server) authorizeContext(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return ctx, nil
+	}
+	if s.isAdminToken(values[0]) {
+		return context.WithValue(ctx, adminContextKey{}, true), nil
+	}
+	if s.keys == nil {
+		return ctx, nil
+	}
+	key, err := s.authorize(values[0])
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return withAPIKey(ctx, key), nil
+}
+
+func (s *Server) authUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := s.authorizeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (cs *contextStream) Context() context.Context {
+	return cs.ctx
+}
+
+func (s *Server) authStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authorizeContext(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+}
+
+// limitUnaryInterceptor classifies and rate limits claims on their network
+// like the limiter middleware of the HTTP API.
+func (s *Server) limitUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	claimReq, ok := req.(*faucetv1.ClaimRequest)
+	if info.FullMethod != grpcClaimMethod || !ok {
+		return handler(ctx, req)
+	}
+	network, ok := s.lookupNetwork(claimReq.Network)
+	if !ok {
+		// The service rejects the unknown network
+		return handler(ctx, req)
+	}
+	if !chain.IsValidAddress(claimReq.Address, true) {
+		network.countClaim(outcomeInvalid)
+		return nil, grpcError(newAPIError(ErrInvalidAddress, "invalid address"))
+	}
+
+	ctx = withClaimClass(ctx, network.classify(ctx, claimReq.Address))
+	clientIP := grpcClientIP(ctx, network.cfg.ProxyCount)
+	limit, release, apiErr := network.limiter.reserve(ctx, claimReq.Address, clientIP)
+	if limit != nil {
+		grpc.SetHeader(ctx, metadata.Pairs(
+			"ratelimit-limit", strconv.Itoa(limit.limit),
+			"ratelimit-remaining", strconv.Itoa(limit.remaining),
+			"ratelimit-reset", strconv.Itoa(ceilSeconds(limit.reset)),
+		))
+	}
+	if apiErr != nil {
+		return nil, grpcError(apiErr)
+	}
+
+	resp, err := handler(withClientIP(ctx, clientIP), req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	logger(ctx).WithFields(log.Fields{
+		"address":  claimReq.Address,
+		"clientIP": clientIP,
+	}).Info("Maximum request limit has been reached")
+	return resp, nil
+}
+
+func grpcClientIP(ctx context.Context, proxyCount int) string {
+	var remoteAddr, xForwardedFor string
+	if p, ok := peer.FromContext(ctx); ok {
+		remoteAddr = p.Addr.String()
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("x-forwarded-for"); len(values) > 0 {
+			xForwardedFor = values[0]
+		}
+	}
+	return getClientIP(proxyCount, xForwardedFor, remoteAddr)
+}
+
+// network returns the network of a request, the default one if it names none.
+func (g *grpcService) network(name string) (*Server, error) {
+	network, ok := g.s.lookupNetwork(name)
+	if !ok {
+		return nil, grpcError(newAPIError(ErrNotFound, "Unknown network %q", name))
+	}
+	return network, nil
+}
+
+func (s *Server) claimMessage(record claimRecord) *faucetv1.Claim {
+	position, queued := s.queue.position(record.ID)
+	return &faucetv1.Claim{
+		Id:          record.ID,
+		Address:     record.Address,
+		Class:       record.Class,
+		Amount:      record.Amount,
+		BatchId:     record.Batch,
+		Status:      claimStatuses[record.Status],
+		TxHash:      record.TxHash,
+		BlockNumber: record.BlockNumber,
+		Queued:      queued,
+		Position:    int32(position),
+		ErrorCode:   string(record.Code),
+		Error:       record.Error,
+	}
+}
+
+// redactedClaimMessage returns the claim as published on the activity feed.
+func (s *Server) redactedClaimMessage(record claimRecord) *faucetv1.Claim {
+	feed := s.feed
+	msg := s.claimMessage(record)
+	msg.Id = feed.claimRef(record.ID)
+	msg.Address = redactAddress(record.Address, feed.redaction)
+	msg.TxHash = redactTxHash(record.TxHash, feed.redaction)
+	return msg
+}
+
+func (g *grpcService) Claim(ctx context.Context, req *faucetv1.ClaimRequest) (*faucetv1.ClaimResponse, error) {
+	if !chain.IsValidAddress(req.Address, true) {
+		return nil, grpcError(newAPIError(ErrInvalidAddress, "invalid address"))
+	}
+	network, nerr := g.network(req.Network)
+	if nerr != nil {
+		return nil, nerr
+	}
+	item, _, err := network.claim(ctx, req.Address)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	record, _ := network.tracker.get(item.ID)
+	return &faucetv1.ClaimResponse{Claim: network.claimMessage(record)}, nil
+}
+
+func (g *grpcService) GetClaim(ctx context.Context, req *faucetv1.GetClaimRequest) (*faucetv1.GetClaimResponse, error) {
+	network, err := g.network(req.Network)
+	if err != nil {
+		return nil, err
+	}
+	record, ok := network.tracker.get(req.Id)
+	if !ok {
+		return nil, grpcError(errClaimMissing)
+	}
+	return &faucetv1.GetClaimResponse{Claim: network.claimMessage(record)}, nil
+}
+
+func (g *grpcService) Info(ctx context.Context, req *faucetv1.InfoRequest) (*faucetv1.InfoResponse, error) {
+	network, err := g.network(req.Network)
+	if err != nil {
+		return nil, err
+	}
+	return &faucetv1.InfoResponse{
+		Account: network.Sender().String(),
+		Network: network.cfg.Network(),
+		Payout:  strconv.Itoa(network.cfg.Payout()),
+	}, nil
+}
+
+func (g *grpcService) StreamClaims(req *faucetv1.StreamClaimsRequest, stream faucetv1.FaucetService_StreamClaimsServer) error {
+	if len(req.Ids) > maxStreamedClaims {
+		return grpcError(newAPIError(ErrInvalidRequest, "At most %d claims can be streamed", maxStreamedClaims))
+	}
+	network, err := g.network(req.Network)
+	if err != nil {
+		return err
+	}
+	// Every claim is only streamed to known callers, and redacted like the activity feed
+	message := network.claimMessage
+	if len(req.Ids) == 0 {
+		if apiKeyFromContext(stream.Context()) == nil && !isAdminContext(stream.Context()) {
+			return grpcError(newAPIError(ErrUnauthorized, "Streaming every claim requires an API key or the admin token"))
+		}
+		message = network.redactedClaimMessage
+	}
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	// Subscribe before reading the records so that no change is missed in between
+	ids := req.Ids
+	if len(ids) == 0 {
+		ids = []string{allClaims}
+	}
+	updates := make(chan claimRecord)
+	for _, id := range ids {
+		ch, unsubscribe := network.tracker.subscribe(id)
+		defer unsubscribe()
+		go func(ch <-chan claimRecord) {
+			for {
+				select {
+				case record := <-ch:
+					select {
+					case updates <- record:
+					case <-ctx.Done():
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(ch)
+	}
+
+	pending := make(map[string]bool)
+	for _, id := range req.Ids {
+		record, ok := network.tracker.get(id)
+		if !ok {
+			return grpcError(errClaimMissing)
+		}
+		if err := stream.Send(&faucetv1.StreamClaimsResponse{Claim: network.claimMessage(record)}); err != nil {
+			return err
+		}
+		if !record.done() {
+			pending[id] = true
+		}
+	}
+	if len(req.Ids) > 0 && len(pending) == 0 {
+		return nil
+	}
+
+	for {
+		select {
+		case record := <-updates:
+			if err := stream.Send(&faucetv1.StreamClaimsResponse{Claim: message(record)}); err != nil {
+				return err
+			}
+			if len(req.Ids) == 0 || !record.done() {
+				continue
+			}
+			delete(pending, record.ID)
+			if len(pending) == 0 {
+				return nil
+			}
+		case <-network.closing:
+			return grpcError(errShuttingDown)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func serveGRPC(srv *grpc.Server, ln net.Listener) {
+	log.Infof("Starting grpc server %s", ln.Addr())
+	if err := srv.Serve(ln); err != nil {
+		log.WithError(err).Error("The grpc server stopped unexpectedly")
+	}
+}
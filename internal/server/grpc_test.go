@@ -0,0 +1,210 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	faucetv1 "github.com/chainflag/eth-faucet/api/faucet/v1"
+	"github.com/chainflag/eth-faucet/internal/apikey"
+	"github.com/chainflag/eth-faucet/internal/chain"
+)
+
+// dialGRPC serves the gRPC API of the server in memory for the duration of the test.
+func dialGRPC(t *testing.T, s *Server) faucetv1.FaucetServiceClient {
+	t.Helper()
+	ln := bufconn.Listen(1024 * 1024)
+	srv := s.newGRPCServer()
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+	conn, err := grpc.Dial("bufnet", grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return ln.DialContext(ctx)
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return faucetv1.NewFaucetServiceClient(conn)
+}
+
+func TestGRPCService(t *testing.T) {
+	store, err := apikey.Open(filepath.Join(t.TempDir(), "apikeys.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, _, err := store.Create(apikey.Key{Name: "ci", Quota: 1, Window: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newTestServer(t, &mockTxBuilder{}, testConfig())
+	s.keys = store
+	client := dialGRPC(t, s)
+	ctx := context.Background()
+	address := "0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B"
+
+	resp, err := client.Claim(ctx, &faucetv1.ClaimRequest{Address: address})
+	if err != nil || resp.Claim.Status != faucetv1.ClaimStatus_CLAIM_STATUS_BROADCAST {
+		t.Fatalf("Claim() = %v, error = %v", resp, err)
+	}
+	// The reset is rounded up like the RateLimit-Reset header of the HTTP API
+	var header metadata.MD
+	if _, err := client.Claim(ctx, &faucetv1.ClaimRequest{Address: address}, grpc.Header(&header)); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("repeated Claim() error = %v, want %s", err, codes.ResourceExhausted)
+	}
+	if reset := header.Get("ratelimit-reset"); len(reset) != 1 || reset[0] != strconv.Itoa(s.cfg.Interval()*60) {
+		t.Errorf("ratelimit-reset = %v, want %d", reset, s.cfg.Interval()*60)
+	}
+	if got, err := client.GetClaim(ctx, &faucetv1.GetClaimRequest{Id: resp.Claim.Id}); err != nil || got.Claim.TxHash != resp.Claim.TxHash {
+		t.Errorf("GetClaim() = %v, error = %v", got, err)
+	}
+	if info, err := client.Info(ctx, &faucetv1.InfoRequest{}); err != nil || info.Network != "testnet" {
+		t.Errorf("Info() = %v, error = %v", info, err)
+	}
+
+	authorized := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+secret)
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		req    *faucetv1.ClaimRequest
+		code   codes.Code
+		reason ErrorCode
+	}{
+		{name: "rate limited", ctx: ctx, req: &faucetv1.ClaimRequest{Address: address}, code: codes.ResourceExhausted, reason: ErrRateLimited},
+		{name: "invalid address", ctx: ctx, req: &faucetv1.ClaimRequest{Address: "0xinvalid"}, code: codes.InvalidArgument, reason: ErrInvalidAddress},
+		{name: "key within quota", ctx: authorized, req: &faucetv1.ClaimRequest{Address: address}, code: codes.OK},
+		{name: "key quota used up", ctx: authorized, req: &faucetv1.ClaimRequest{Address: address}, code: codes.ResourceExhausted, reason: ErrQuotaExceeded},
+		{name: "unknown key", ctx: metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer faucet_unknown"), req: &faucetv1.ClaimRequest{Address: address}, code: codes.Unauthenticated, reason: ErrInvalidAPIKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.Claim(tt.ctx, tt.req)
+			st := status.Convert(err)
+			if st.Code() != tt.code {
+				t.Fatalf("Claim() code = %s, want %s: %v", st.Code(), tt.code, err)
+			}
+			if tt.reason == "" {
+				return
+			}
+			if len(st.Details()) == 0 {
+				t.Fatal("Claim() error has no details")
+			}
+			if info, ok := st.Details()[0].(*errdetails.ErrorInfo); !ok || info.Reason != string(tt.reason) {
+				t.Errorf("Claim() error details = %v, want reason %s", st.Details(), tt.reason)
+			}
+		})
+	}
+
+	stream, err := client.StreamClaims(ctx, &faucetv1.StreamClaimsRequest{Ids: []string{resp.Claim.Id}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg, err := stream.Recv(); err != nil || msg.Claim.Status != faucetv1.ClaimStatus_CLAIM_STATUS_BROADCAST {
+		t.Fatalf("StreamClaims() = %v, error = %v", msg, err)
+	}
+	s.tracker.reportBlock(resp.Claim.Id, statusConfirmed, 1, nil)
+	if msg, err := stream.Recv(); err != nil || msg.Claim.Status != faucetv1.ClaimStatus_CLAIM_STATUS_CONFIRMED {
+		t.Fatalf("StreamClaims() = %v, error = %v", msg, err)
+	}
+	if _, err := stream.Recv(); err == nil {
+		t.Error("StreamClaims() did not end after the claim was confirmed")
+	}
+}
+
+func TestGRPCStreamAllClaims(t *testing.T) {
+	cfg := testConfig()
+	cfg.Admin.Token = "secret"
+	s := newTestServer(t, &mockTxBuilder{}, cfg)
+	client := dialGRPC(t, s)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.StreamClaims(ctx, &faucetv1.StreamClaimsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("anonymous StreamClaims() error = %v, want %s", err, codes.Unauthenticated)
+	}
+
+	authorized := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer secret", requestIDHeader, "stream-1")
+	stream, err = client.StreamClaims(authorized, &faucetv1.StreamClaimsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The claim is reported once the call has subscribed to the tracker
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		s.tracker.mutex.RLock()
+		subscribed := len(s.tracker.subscribers[allClaims]) > 0
+		s.tracker.mutex.RUnlock()
+		if subscribed {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	item := &queueItem{ID: "claim", Address: "0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B", Class: publicClassName, Amount: chain.EtherToWei(1)}
+	s.tracker.report(item, statusBroadcast, "0x01", nil)
+	msg, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Claim.Id == item.ID || msg.Claim.Address != "0xAb58…eC9B" || msg.Claim.TxHash != "" {
+		t.Errorf("StreamClaims() = %v, want the claim redacted like the activity feed", msg.Claim)
+	}
+	if header, err := stream.Header(); err != nil || fmt.Sprint(header.Get(requestIDHeader)) != "[stream-1]" {
+		t.Errorf("StreamClaims() header = %v, error = %v, want the request ID", header, err)
+	}
+}
+
+func TestGRPCNetworks(t *testing.T) {
+	s := newTestServer(t, &mockTxBuilder{}, testConfig())
+	sepoliaBuilder := &mockTxBuilder{}
+	cfg := testConfig()
+	cfg.Faucet.Name = "Sepolia"
+	sepolia := newTestServer(t, sepoliaBuilder, cfg)
+	s.AddNetwork("sepolia", sepolia)
+	client := dialGRPC(t, s)
+	ctx := context.Background()
+	address := "0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B"
+
+	if info, err := client.Info(ctx, &faucetv1.InfoRequest{Network: "sepolia"}); err != nil || info.Network != "Sepolia" {
+		t.Errorf("Info() = %v, error = %v", info, err)
+	}
+	resp, err := client.Claim(ctx, &faucetv1.ClaimRequest{Address: address, Network: "sepolia"})
+	if err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	if len(sepoliaBuilder.sent()) != 1 {
+		t.Errorf("Claim() sent %d transfers on sepolia, want 1", len(sepoliaBuilder.sent()))
+	}
+	if _, err := client.GetClaim(ctx, &faucetv1.GetClaimRequest{Id: resp.Claim.Id, Network: "sepolia"}); err != nil {
+		t.Errorf("GetClaim() error = %v", err)
+	}
+	if _, err := client.GetClaim(ctx, &faucetv1.GetClaimRequest{Id: resp.Claim.Id}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetClaim() on the default network error = %v, want %s", err, codes.NotFound)
+	}
+
+	// Networks are rate limited on their own
+	if _, err := client.Claim(ctx, &faucetv1.ClaimRequest{Address: address, Network: "sepolia"}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("second Claim() on sepolia error = %v, want %s", err, codes.ResourceExhausted)
+	}
+	if _, err := client.Claim(ctx, &faucetv1.ClaimRequest{Address: address}); err != nil {
+		t.Errorf("Claim() on the default network error = %v", err)
+	}
+	if _, err := client.Info(ctx, &faucetv1.InfoRequest{Network: "goerli"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Info() of an unknown network error = %v, want %s", err, codes.NotFound)
+	}
+}
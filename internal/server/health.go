@@ -0,0 +1,165 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chainflag/eth-faucet/internal/chain"
+)
+
+const (
+	checkOK   = "ok"
+	checkFail = "fail"
+
+	readinessTimeout = 5 * time.Second
+)
+
+// healthCheck is the result of one readiness check. Failures are described
+// without internal details such as provider URLs, which are logged instead.
+type healthCheck struct {
+	Network string `json:"network,omitempty"`
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+type healthResponse struct {
+	Status string        `json:"status"`
+	Checks []healthCheck `json:"checks,omitempty"`
+}
+
+// handleHealth reports that the process is alive and serving requests.
+func (s *Server) handleHealth() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			http.NotFound(w, r)
+			return
+		}
+		renderJSON(w, healthResponse{Status: checkOK}, http.StatusOK)
+	}
+}
+
+// handleReady runs the readiness checks of all networks and responds with 503
+// Service Unavailable if any of them fails.
+func (s *Server) handleReady() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			http.NotFound(w, r)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		defer cancel()
+
+		networks := s.allNetworks()
+		results := make([][]healthCheck, len(networks))
+		var wg sync.WaitGroup
+		for i, network := range networks {
+			wg.Add(1)
+			go func(i int, network *Server) {
+				defer wg.Done()
+				results[i] = network.checkReadiness(ctx)
+			}(i, network)
+		}
+		wg.Wait()
+
+		res := healthResponse{Status: checkOK}
+		for _, checks := range results {
+			for _, check := range checks {
+				if check.Status != checkOK {
+					res.Status = checkFail
+				}
+				res.Checks = append(res.Checks, check)
+			}
+		}
+		status := http.StatusOK
+		if res.Status != checkOK {
+			status = http.StatusServiceUnavailable
+		}
+		renderJSON(w, res, status)
+	}
+}
+
+// checkReadiness checks the provider, the chain ID, the balance, the signer
+// and the queue of the network. The default network also checks the stores
+// shared by all networks.
+func (s *Server) checkReadiness(ctx context.Context) []healthCheck {
+	var checks []healthCheck
+	add := func(name, message string, err error) {
+		check := healthCheck{Network: s.network, Name: name, Status: checkOK, Message: message}
+		if err != nil {
+			check.Status = checkFail
+			logger(ctx).WithError(err).WithField("check", name).Warn("Readiness check failed")
+		}
+		checks = append(checks, check)
+	}
+
+	if verifier, ok := s.TxBuilder.(chain.ChainVerifier); ok {
+		err := verifier.VerifyChainID(ctx)
+		if errors.Is(err, chain.ErrChainIDMismatch) {
+			add("rpc", "", nil)
+			add("chainId", "provider is on another chain", err)
+		} else if err != nil {
+			add("rpc", "provider is unreachable", err)
+			add("chainId", "chain ID is unknown", err)
+		} else {
+			add("rpc", "", nil)
+			add("chainId", "", nil)
+		}
+	}
+
+	if reader, ok := s.TxBuilder.(chain.BalanceReader); ok {
+		balance, err := reader.Balance(ctx)
+		floor := s.monitor.loadLimits().floor
+		switch {
+		case err != nil:
+			add("balance", "balance is unknown", err)
+		case floor.Sign() > 0 && balance.Cmp(floor) < 0:
+			add("balance", fmt.Sprintf("%s ETH is below the floor of %s ETH", formatEther(balance), formatEther(floor)), errLowBalance)
+		default:
+			add("balance", formatEther(balance)+" ETH", nil)
+		}
+	}
+
+	if checker, ok := s.TxBuilder.(chain.SignerChecker); ok {
+		if err := checker.CheckSigner(); err != nil {
+			add("signer", "signer is unavailable", err)
+		} else {
+			add("signer", s.Sender().Hex(), nil)
+		}
+	}
+
+	if full := s.queue.fullLanes(); len(full) > 0 {
+		add("queue", "queue is full: "+strings.Join(full, ", "), errQueueFull)
+	} else {
+		add("queue", fmt.Sprintf("%d queued", s.queue.len()), nil)
+	}
+
+	if s.network != "" {
+		return checks
+	}
+	if s.keys != nil {
+		_, err := s.keys.List()
+		add("apikeys", storeMessage(err), err)
+	}
+	if s.audit != nil {
+		err := s.audit.Check()
+		add("audit", storeMessage(err), err)
+	}
+	if s.history != nil {
+		err := s.history.Check()
+		add("history", storeMessage(err), err)
+	}
+	return checks
+}
+
+func storeMessage(err error) string {
+	if err != nil {
+		return "store is unavailable"
+	}
+	return ""
+}
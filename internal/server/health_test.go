@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chainflag/eth-faucet/internal/chain"
+)
+
+// probedTxBuilder implements the interfaces used by the readiness checks.
+type probedTxBuilder struct {
+	mockTxBuilder
+	chainErr  error
+	balance   *big.Int
+	signerErr error
+}
+
+func (b *probedTxBuilder) VerifyChainID(context.Context) error {
+	return b.chainErr
+}
+
+func (b *probedTxBuilder) Balance(context.Context) (*big.Int, error) {
+	return b.balance, nil
+}
+
+func (b *probedTxBuilder) CheckSigner() error {
+	return b.signerErr
+}
+
+func TestReadiness(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder *probedTxBuilder
+		queued  int
+		failed  []string
+	}{
+		{name: "ready", builder: &probedTxBuilder{balance: chain.EtherToWei(10)}},
+		{name: "provider unreachable", builder: &probedTxBuilder{chainErr: errors.New("connection refused"), balance: chain.EtherToWei(10)}, failed: []string{"rpc", "chainId"}},
+		{name: "chain switched", builder: &probedTxBuilder{chainErr: fmt.Errorf("%w: provider is on chain 1", chain.ErrChainIDMismatch), balance: chain.EtherToWei(10)}, failed: []string{"chainId"}},
+		{name: "below floor", builder: &probedTxBuilder{balance: chain.EtherToWei(1)}, failed: []string{"balance"}},
+		{name: "signer unavailable", builder: &probedTxBuilder{balance: chain.EtherToWei(10), signerErr: errors.New("no key")}, failed: []string{"signer"}},
+		{name: "queue saturated", builder: &probedTxBuilder{balance: chain.EtherToWei(10)}, queued: 10, failed: []string{"queue"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.Monitor.Floor = 2
+			s := newTestServer(t, tt.builder, cfg)
+			for i := 0; i < tt.queued; i++ {
+				s.queue.push(&queueItem{Address: "0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B", Class: publicClassName})
+			}
+
+			rec := httptest.NewRecorder()
+			s.setupRouter().ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+			var res healthResponse
+			if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+				t.Fatalf("failed to decode readiness: %v", err)
+			}
+			if len(res.Checks) != 5 {
+				t.Fatalf("checks = %+v, want rpc, chainId, balance, signer and queue", res.Checks)
+			}
+			var failed []string
+			for _, check := range res.Checks {
+				if check.Status != checkOK {
+					failed = append(failed, check.Name)
+				}
+			}
+			if fmt.Sprint(failed) != fmt.Sprint(tt.failed) {
+				t.Errorf("failed checks = %v, want %v", failed, tt.failed)
+			}
+			wantStatus := http.StatusOK
+			if len(tt.failed) > 0 {
+				wantStatus = http.StatusServiceUnavailable
+			}
+			if rec.Code != wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, wantStatus)
+			}
+		})
+	}
+}
+
+func TestLiveness(t *testing.T) {
+	s := newTestServer(t, &probedTxBuilder{chainErr: errors.New("connection refused")}, testConfig())
+	rec := httptest.NewRecorder()
+	s.setupRouter().ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
@@ -1,8 +1,7 @@
 package server
 
 import (
-	"errors"
-	"fmt"
+	"context"
 	"net"
 	"net/http"
 	"strings"
@@ -12,76 +11,199 @@ import (
 	"github.com/jellydator/ttlcache/v2"
 	log "github.com/sirupsen/logrus"
 	"github.com/urfave/negroni"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/chainflag/eth-faucet/internal/apikey"
 )
 
 type Limiter struct {
 	mutex      sync.Mutex
 	cache      *ttlcache.Cache
+	quotas     *ttlcache.Cache
 	proxyCount int
 	ttl        time.Duration
+	// network labels the metrics of the limiter
+	network string
+}
+
+type quotaUsage struct {
+	count int
+	reset time.Time
 }
 
 func NewLimiter(proxyCount int, ttl time.Duration) *Limiter {
 	cache := ttlcache.NewCache()
 	cache.SkipTTLExtensionOnHit(true)
+	// Quota usage expires with its window, so that idle keys are forgotten
+	quotas := ttlcache.NewCache()
+	quotas.SkipTTLExtensionOnHit(true)
 	return &Limiter{
 		cache:      cache,
+		quotas:     quotas,
 		proxyCount: proxyCount,
 		ttl:        ttl,
+		network:    defaultNetworkLabel,
 	}
 }
 
 func (l *Limiter) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
 	address, err := readAddress(r)
 	if err != nil {
-		var mr *malformedRequest
-		if errors.As(err, &mr) {
-			renderJSON(w, claimResponse{Message: mr.message}, mr.status)
-		} else {
-			renderJSON(w, claimResponse{Message: http.StatusText(http.StatusInternalServerError)}, http.StatusInternalServerError)
-		}
+		renderReadError(w, r, err)
 		return
 	}
 
-	if l.ttl <= 0 {
-		next.ServeHTTP(w, r)
-		return
+	clientIP := getClientIPFromRequest(l.proxyCount, r)
+	limit, release, apiErr := l.reserve(r.Context(), address, clientIP)
+	if limit != nil {
+		limit.setHeaders(w)
 	}
-
-	clintIP := getClientIPFromRequest(l.proxyCount, r)
-	l.mutex.Lock()
-	if l.limitByKey(w, address) || l.limitByKey(w, clintIP) {
-		l.mutex.Unlock()
+	if apiErr != nil {
+		renderError(w, r, apiErr)
 		return
 	}
-	l.cache.SetWithTTL(address, true, l.ttl)
-	l.cache.SetWithTTL(clintIP, true, l.ttl)
-	l.mutex.Unlock()
 
-	next.ServeHTTP(w, r)
+	next.ServeHTTP(w, r.WithContext(withClientIP(r.Context(), clientIP)))
 	if w.(negroni.ResponseWriter).Status() != http.StatusOK {
-		l.cache.Remove(address)
-		l.cache.Remove(clintIP)
+		release()
 		return
 	}
-	log.WithFields(log.Fields{
+	logger(r.Context()).WithFields(log.Fields{
 		"address":  address,
-		"clientIP": clintIP,
+		"clientIP": clientIP,
 	}).Info("Maximum request limit has been reached")
 }
 
-func (l *Limiter) limitByKey(w http.ResponseWriter, key string) bool {
-	if _, ttl, err := l.cache.GetWithTTL(key); err == nil {
-		errMsg := fmt.Sprintf("You have exceeded the rate limit. Please wait %s before you try again", ttl.Round(time.Second))
-		renderJSON(w, claimResponse{Message: errMsg}, http.StatusTooManyRequests)
-		return true
+// rateLimit is the state of the rate limit applied to a claim.
+type rateLimit struct {
+	limit     int
+	remaining int
+	reset     time.Duration
+}
+
+func (rl *rateLimit) setHeaders(w http.ResponseWriter) {
+	setRateLimitHeaders(w, rl.limit, rl.remaining, rl.reset)
+}
+
+// reserve takes a claim from the rate limit of the API key, or of the address
+// and client IP within their claim class. The returned function releases the
+// claim again if it fails.
+func (l *Limiter) reserve(ctx context.Context, address, clientIP string) (_ *rateLimit, _ func(), apiErr *apiError) {
+	_, span := tracer.Start(ctx, "Limiter.reserve")
+	defer func() {
+		result := "allowed"
+		if apiErr != nil {
+			result = "limited"
+			claimsTotal.WithLabelValues(l.network, outcomeRateLimited).Inc()
+		}
+		limiterChecks.WithLabelValues(l.network, result).Inc()
+		span.SetAttributes(attribute.String("faucet.limiter.result", result))
+		endSpan(span, apiErr)
+	}()
+
+	// API keys are limited by their own quota instead of address and IP
+	if key := apiKeyFromContext(ctx); key != nil {
+		limit, err := l.reserveQuota(key, 1)
+		return limit, func() { l.returnQuota(key, 1) }, err
+	}
+
+	l.mutex.Lock()
+	ttl := l.ttl
+	l.mutex.Unlock()
+	addressKey, ipKey := address, clientIP
+	// Priority classes carry their own interval and are limited separately
+	if class := claimClassFromContext(ctx); class != nil {
+		ttl = class.ttl()
+		addressKey, ipKey = class.limitKey(address), class.limitKey(clientIP)
+	}
+	if ttl <= 0 {
+		return nil, func() {}, nil
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for _, key := range []string{addressKey, ipKey} {
+		if _, wait, err := l.cache.GetWithTTL(key); err == nil {
+			return &rateLimit{limit: 1, reset: wait}, nil, rateLimited(ErrRateLimited, wait, "You have exceeded the rate limit. Please wait %s before you try again", wait.Round(time.Second))
+		}
+	}
+	l.cache.SetWithTTL(addressKey, true, ttl)
+	l.cache.SetWithTTL(ipKey, true, ttl)
+	return &rateLimit{limit: 1, reset: ttl}, func() {
+		l.cache.Remove(addressKey)
+		l.cache.Remove(ipKey)
+	}, nil
+}
+
+func (l *Limiter) SetTTL(ttl time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.ttl = ttl
+}
+
+// Reset clears the rate limit of an address, client IP or API key ID. Keys of
+// priority classes are prefixed with the class name, e.g. "internal/0x...".
+func (l *Limiter) Reset(key string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	quota := l.quotas.Remove(key) == nil
+	return l.cache.Remove(key) == nil || quota
+}
+
+// reserveQuota takes n claims from the quota of the key. Keys without a quota
+// are not limited.
+func (l *Limiter) reserveQuota(key *apikey.Key, n int) (*rateLimit, *apiError) {
+	if key.Quota <= 0 || key.Window <= 0 {
+		return nil, nil
+	}
+
+	remaining, reset, ok := l.takeQuota(key, n)
+	limit := &rateLimit{limit: key.Quota, remaining: remaining, reset: reset}
+	if !ok {
+		return limit, rateLimited(ErrQuotaExceeded, reset, "API key quota of %d claims does not allow %d more. Please wait %s before you try again", key.Quota, n, reset.Round(time.Second))
+	}
+	return limit, nil
+}
+
+// takeQuota reserves n claims from the quota of the key. It returns the claims
+// left in the quota window and the time until the window resets.
+func (l *Limiter) takeQuota(key *apikey.Key, n int) (int, time.Duration, bool) {
+	now := time.Now()
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	var usage *quotaUsage
+	if value, err := l.quotas.Get(key.ID); err == nil {
+		usage = value.(*quotaUsage)
+	}
+	if usage == nil || now.After(usage.reset) {
+		usage = &quotaUsage{reset: now.Add(key.QuotaWindow())}
+		l.quotas.SetWithTTL(key.ID, usage, key.QuotaWindow())
+	}
+	if usage.count+n > key.Quota {
+		return key.Quota - usage.count, usage.reset.Sub(now), false
+	}
+	usage.count += n
+	return key.Quota - usage.count, usage.reset.Sub(now), true
+}
+
+func (l *Limiter) returnQuota(key *apikey.Key, n int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if value, err := l.quotas.Get(key.ID); err == nil {
+		usage := value.(*quotaUsage)
+		usage.count -= n
+		if usage.count < 0 {
+			usage.count = 0
+		}
 	}
-	return false
 }
 
 func getClientIPFromRequest(proxyCount int, r *http.Request) string {
+	return getClientIP(proxyCount, r.Header.Get("X-Forwarded-For"), r.RemoteAddr)
+}
+
+func getClientIP(proxyCount int, xForwardedFor, remoteAddr string) string {
 	if proxyCount > 0 {
-		xForwardedFor := r.Header.Get("X-Forwarded-For")
 		if xForwardedFor != "" {
 			xForwardedForParts := strings.Split(xForwardedFor, ",")
 			// Avoid reading the user's forged request header by configuring the count of reverse proxies
@@ -93,9 +215,9 @@ func getClientIPFromRequest(proxyCount int, r *http.Request) string {
 		}
 	}
 
-	remoteIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	remoteIP, _, err := net.SplitHostPort(remoteAddr)
 	if err != nil {
-		remoteIP = r.RemoteAddr
+		remoteIP = remoteAddr
 	}
 	return remoteIP
 }
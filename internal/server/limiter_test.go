@@ -0,0 +1,100 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/urfave/negroni"
+
+	"github.com/chainflag/eth-faucet/internal/apikey"
+)
+
+func TestLimiterAPIKeyQuota(t *testing.T) {
+	store, err := apikey.Open(filepath.Join(t.TempDir(), "apikeys.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, _, err := store.Create(apikey.Key{Name: "ci", Quota: 2, Window: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestServer(t, &mockTxBuilder{}, testConfig())
+	s.keys = store
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		renderJSON(w, claimResponse{Message: "ok"}, http.StatusOK)
+	})
+	handler := negroni.New(negroni.HandlerFunc(s.authenticate), negroni.HandlerFunc(s.classifyClaim),
+		NewLimiter(0, time.Hour), negroni.Wrap(ok))
+
+	tests := []struct {
+		name      string
+		auth      string
+		status    int
+		code      ErrorCode
+		remaining string
+	}{
+		{name: "anonymous", auth: "", status: http.StatusOK, remaining: "0"},
+		{name: "anonymous limited", auth: "", status: http.StatusTooManyRequests, code: ErrRateLimited, remaining: "0"},
+		{name: "key bypasses address limit", auth: "Bearer " + secret, status: http.StatusOK, remaining: "1"},
+		{name: "key within quota", auth: "Bearer " + secret, status: http.StatusOK, remaining: "0"},
+		{name: "key quota used up", auth: "Bearer " + secret, status: http.StatusTooManyRequests, code: ErrQuotaExceeded, remaining: "0"},
+		{name: "unknown key", auth: "Bearer faucet_unknown", status: http.StatusUnauthorized, code: ErrInvalidAPIKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := strings.NewReader(`{"address":"0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B"}`)
+			req := httptest.NewRequest(http.MethodPost, "/api/claim", body)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			var resp claimResponse
+			json.NewDecoder(rec.Body).Decode(&resp)
+			if rec.Code != tt.status || resp.Code != tt.code {
+				t.Errorf("ServeHTTP() = %d %s, want %d %s", rec.Code, resp.Code, tt.status, tt.code)
+			}
+			if got := rec.Header().Get("RateLimit-Remaining"); got != tt.remaining {
+				t.Errorf("RateLimit-Remaining = %q, want %q", got, tt.remaining)
+			}
+			if retryAfter := rec.Header().Get("Retry-After"); (retryAfter != "") != (rec.Code == http.StatusTooManyRequests) {
+				t.Errorf("Retry-After = %q with status %d", retryAfter, rec.Code)
+			}
+		})
+	}
+}
+
+func TestLimiterQuotaExpires(t *testing.T) {
+	l := NewLimiter(0, time.Hour)
+	key := &apikey.Key{ID: "ci", Quota: 2, Window: 60}
+	if _, err := l.reserveQuota(key, 1); err != nil {
+		t.Fatal(err)
+	}
+	// The usage of an idle key is dropped once its window is over
+	if _, ttl, err := l.quotas.GetWithTTL(key.ID); err != nil || ttl <= 0 || ttl > key.QuotaWindow() {
+		t.Errorf("quota usage ttl = %v, %v, want the quota window", ttl, err)
+	}
+}
+
+func TestAuthenticateWithoutKeyStore(t *testing.T) {
+	s := newTestServer(t, &mockTxBuilder{}, testConfig())
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		renderJSON(w, claimResponse{Message: "ok"}, http.StatusOK)
+	})
+	handler := negroni.New(negroni.HandlerFunc(s.authenticate), negroni.Wrap(ok))
+
+	// The header of a basic auth proxy is passed through
+	req := httptest.NewRequest(http.MethodPost, "/api/claim", nil)
+	req.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("ServeHTTP() = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
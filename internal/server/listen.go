@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const unixPrefix = "unix:"
+
+// listenAddress returns the network and address of a listener on the host
+// and port. A host of the form unix:/path is a Unix domain socket, and an
+// empty host binds all interfaces.
+func listenAddress(host string, port int) (string, string) {
+	if strings.HasPrefix(host, unixPrefix) {
+		return "unix", strings.TrimPrefix(host, unixPrefix)
+	}
+	return "tcp", net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// listenerEnabled reports whether an optional listener is configured, by a
+// port or a Unix socket.
+func listenerEnabled(host string, port int) bool {
+	return port > 0 || strings.HasPrefix(host, unixPrefix)
+}
+
+// listen binds the host and port, wrapping the listener in TLS if it is
+// configured. The socket file of an earlier run is removed first.
+func listen(ctx context.Context, host string, port int, tlsConfig *tls.Config) (net.Listener, error) {
+	network, address := listenAddress(host, port)
+	if network == "unix" {
+		if err := removeStaleSocket(address); err != nil {
+			return nil, err
+		}
+	}
+	var lc net.ListenConfig
+	ln, err := lc.Listen(ctx, network, address)
+	if err != nil || tlsConfig == nil {
+		return ln, err
+	}
+	return tls.NewListener(ln, tlsConfig), nil
+}
+
+// removeStaleSocket removes the socket file at the path unless another
+// process still accepts connections on it.
+func removeStaleSocket(path string) error {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return errors.New(path + " exists and is not a socket")
+	}
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		conn.Close()
+		return errors.New(path + " is in use by another process")
+	} else if !errors.Is(err, syscall.ECONNREFUSED) {
+		return err
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestListenAddress(t *testing.T) {
+	tests := []struct {
+		host        string
+		port        int
+		wantNetwork string
+		wantAddress string
+	}{
+		{port: 8080, wantNetwork: "tcp", wantAddress: ":8080"},
+		{host: "127.0.0.1", port: 8080, wantNetwork: "tcp", wantAddress: "127.0.0.1:8080"},
+		{host: "::1", port: 8080, wantNetwork: "tcp", wantAddress: "[::1]:8080"},
+		{host: "unix:/run/faucet.sock", port: 8080, wantNetwork: "unix", wantAddress: "/run/faucet.sock"},
+	}
+	for _, tt := range tests {
+		network, address := listenAddress(tt.host, tt.port)
+		if network != tt.wantNetwork || address != tt.wantAddress {
+			t.Errorf("listenAddress(%q, %d) = %s %s, want %s %s", tt.host, tt.port, network, address, tt.wantNetwork, tt.wantAddress)
+		}
+	}
+}
+
+func TestListenUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "faucet.sock")
+	live, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := listen(context.Background(), "unix:"+path, 0, nil); err == nil {
+		t.Fatal("listen() took over the socket of a running listener")
+	}
+
+	// A listener that exits without cleaning up leaves a stale socket behind
+	live.SetUnlinkOnClose(false)
+	live.Close()
+	ln, err := listen(context.Background(), "unix:"+path, 0, nil)
+	if err != nil {
+		t.Fatalf("listen() on a stale socket error = %v", err)
+	}
+	ln.Close()
+}
+
+// unixClient returns a client that connects to the socket whatever the URL.
+func unixClient(path string) *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}}
+}
+
+func TestSeparateListeners(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig()
+	cfg.HTTPAddr = "unix:" + filepath.Join(dir, "public.sock")
+	cfg.Admin.Addr, cfg.Admin.Token = "unix:"+filepath.Join(dir, "admin.sock"), "secret"
+	cfg.Metrics.Addr = "unix:" + filepath.Join(dir, "metrics.sock")
+	s := newTestServer(t, &mockTxBuilder{}, cfg)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer s.Shutdown(context.Background())
+
+	tests := []struct {
+		socket string
+		path   string
+		want   int
+	}{
+		{socket: "public.sock", path: "/api/info", want: http.StatusOK},
+		{socket: "public.sock", path: "/metrics", want: http.StatusNotFound},
+		{socket: "public.sock", path: "/admin/status", want: http.StatusNotFound},
+		{socket: "metrics.sock", path: "/metrics", want: http.StatusOK},
+		{socket: "metrics.sock", path: "/healthz", want: http.StatusOK},
+		{socket: "metrics.sock", path: "/api/info", want: http.StatusNotFound},
+		{socket: "admin.sock", path: "/admin/status", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		resp, err := unixClient(filepath.Join(dir, tt.socket)).Get("http://faucet" + tt.path)
+		if err != nil {
+			t.Fatalf("GET %s on %s error = %v", tt.path, tt.socket, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("GET %s on %s = %d, want %d", tt.path, tt.socket, resp.StatusCode, tt.want)
+		}
+	}
+}
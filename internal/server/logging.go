@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/negroni"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// requestIDPattern limits the request IDs accepted from clients and proxies
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+type requestIDContextKey struct{}
+
+func withRequestID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDContextKey{}, id)
+}
+
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// logger returns a log entry that carries the request ID of ctx, if any.
+func logger(ctx context.Context) *log.Entry {
+	entry := log.NewEntry(log.StandardLogger())
+	if id := requestIDFromContext(ctx); id != "" {
+		entry = entry.WithField("requestId", id)
+	}
+	return entry
+}
+
+// newPipeline returns the middleware shared by the HTTP listeners.
+func newPipeline() *negroni.Negroni {
+	recovery := negroni.NewRecovery()
+	recovery.Logger = log.StandardLogger()
+	return negroni.New(recovery, negroni.HandlerFunc(assignRequestID), negroni.HandlerFunc(logRequest), negroni.HandlerFunc(traceRequest))
+}
+
+// assignRequestID keeps the X-Request-ID of a proxy or client if it is well
+// formed and generates one otherwise. The ID is sent back in the response.
+func assignRequestID(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	id := r.Header.Get(requestIDHeader)
+	if !requestIDPattern.MatchString(id) {
+		id = uuid.NewString()
+	}
+	w.Header().Set(requestIDHeader, id)
+	next(w, r.WithContext(withRequestID(r.Context(), id)))
+}
+
+// logRequest writes an access log line for every request.
+func logRequest(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	start := time.Now()
+	next(w, r)
+	res := w.(negroni.ResponseWriter)
+	logger(r.Context()).WithFields(log.Fields{
+		"method":     r.Method,
+		"path":       r.URL.Path,
+		"status":     res.Status(),
+		"size":       res.Size(),
+		"durationMs": time.Since(start).Milliseconds(),
+		"remoteAddr": r.RemoteAddr,
+	}).Info("Handled request")
+}
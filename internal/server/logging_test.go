@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{name: "generated"},
+		{name: "from proxy", header: "3f2a-proxy.1", want: "3f2a-proxy.1"},
+		{name: "malformed", header: "not a valid id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook := test.NewGlobal()
+			defer hook.Reset()
+			s := newTestServer(t, &mockTxBuilder{}, testConfig())
+			n := newPipeline()
+			n.UseHandler(s.setupRouter())
+
+			req := httptest.NewRequest("POST", "/api/v1/claim", strings.NewReader(`{"address":"0xinvalid"}`))
+			if tt.header != "" {
+				req.Header.Set(requestIDHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			n.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(requestIDHeader)
+			if !requestIDPattern.MatchString(id) || (tt.want != "" && id != tt.want) || (tt.want == "" && id == tt.header) {
+				t.Fatalf("%s = %q, want %q", requestIDHeader, id, tt.want)
+			}
+			var body errorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.RequestID != id {
+				t.Errorf("error requestId = %q, want %q", body.RequestID, id)
+			}
+			if entry := hook.LastEntry(); entry == nil || entry.Data["requestId"] != id || entry.Data["status"] != http.StatusBadRequest {
+				t.Errorf("access log = %v, want the request ID and status", entry)
+			}
+		})
+	}
+}
+
+func TestQueuedTransferRequestID(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+	s := newTestServer(t, &mockTxBuilder{}, testConfig())
+
+	// Hold the lock so that the claim is queued
+	s.mutex.Lock()
+	ctx := withRequestID(context.Background(), "claim-1")
+	if _, _, err := s.claim(ctx, "0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B"); err != nil {
+		t.Fatalf("claim() error = %v", err)
+	}
+	hook.Reset()
+	s.drainQueue(context.Background())
+	s.mutex.Unlock()
+
+	entry := hook.LastEntry()
+	if entry == nil || entry.Level != log.InfoLevel || entry.Data["requestId"] != "claim-1" {
+		t.Errorf("transfer log = %v, want the request ID of the claim", entry)
+	}
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/chainflag/eth-faucet/internal/chain"
+)
+
+const (
+	outcomeFunded      = "funded"
+	outcomeQueued      = "queued"
+	outcomeRateLimited = "rate_limited"
+	outcomeInvalid     = "invalid"
+	outcomeFailed      = "failed"
+
+	// defaultNetworkLabel is the network label of the default network
+	defaultNetworkLabel = "default"
+
+	sampleInterval = 30 * time.Second
+)
+
+var (
+	claimsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "faucet_claims_total",
+		Help: "Claims by outcome: funded, queued, rate_limited, invalid or failed.",
+	}, []string{"network", "outcome"})
+	limiterChecks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "faucet_limiter_checks_total",
+		Help: "Rate limit checks by result: allowed or limited.",
+	}, []string{"network", "result"})
+	queueTransfers = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "faucet_queue_transfers_total",
+		Help: "Transfers sent from the queue by result: sent or failed.",
+	}, []string{"network", "result"})
+	queueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "faucet_queue_depth",
+		Help: "Claims waiting in the queue.",
+	}, []string{"network"})
+	balanceEther = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "faucet_balance_ether",
+		Help: "Balance of the faucet account in Ether.",
+	}, []string{"network"})
+	pendingNonceGap = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "faucet_pending_nonce_gap",
+		Help: "Transactions of the faucet account that are sent but not yet mined.",
+	}, []string{"network"})
+)
+
+// claimOutcome returns the outcome label of a rejected claim.
+func claimOutcome(err *apiError) string {
+	switch {
+	case err.Code == ErrRateLimited || err.Code == ErrQuotaExceeded:
+		return outcomeRateLimited
+	case strings.HasPrefix(string(err.Code), "INVALID_"):
+		return outcomeInvalid
+	default:
+		return outcomeFailed
+	}
+}
+
+// setupMetricsRouter serves the metrics on a listener apart from the public
+// API, along with the health probes for the same reason.
+func (s *Server) setupMetricsRouter() http.Handler {
+	router := http.NewServeMux()
+	router.Handle("/metrics", promhttp.Handler())
+	router.Handle("/healthz", s.handleHealth())
+	router.Handle("/readyz", s.handleReady())
+	return router
+}
+
+func (s *Server) networkLabel() string {
+	if s.network == "" {
+		return defaultNetworkLabel
+	}
+	return s.network
+}
+
+func (s *Server) countClaim(outcome string) {
+	claimsTotal.WithLabelValues(s.networkLabel(), outcome).Inc()
+}
+
+func (s *Server) observeQueue() {
+	queueDepth.WithLabelValues(s.networkLabel()).Set(float64(s.queue.len()))
+}
+
+// sampleMetrics periodically records the pending nonce gap of the faucet
+// account. The balance is recorded by the balance monitor.
+func (s *Server) sampleMetrics() {
+	defer s.workers.Done()
+	nonceReader, ok := s.TxBuilder.(chain.NonceGapReader)
+	if !ok {
+		return
+	}
+
+	sample := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if gap, err := nonceReader.PendingNonceGap(ctx); err == nil {
+			pendingNonceGap.WithLabelValues(s.networkLabel()).Set(float64(gap))
+		} else {
+			log.WithError(err).Debug("Failed to sample the pending nonce gap")
+		}
+	}
+
+	sample()
+	ticker := time.NewTicker(sampleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			sample()
+		case <-s.quit:
+			return
+		}
+	}
+}
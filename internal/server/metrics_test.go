@@ -0,0 +1,43 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/urfave/negroni"
+)
+
+func TestClaimMetrics(t *testing.T) {
+	s := newTestServer(t, &mockTxBuilder{}, testConfig())
+	s.AddNetwork("metrics", newTestServer(t, &mockTxBuilder{}, testConfig()))
+	router := s.setupRouter()
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.RemoteAddr = "192.0.2.1:1234"
+		rec := httptest.NewRecorder()
+		negroni.New(negroni.Wrap(router)).ServeHTTP(rec, req)
+		return rec
+	}
+
+	body := `{"address":"0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B"}`
+	serve("POST", "/api/metrics/claim", body)
+	serve("POST", "/api/metrics/claim", body)
+	serve("POST", "/api/metrics/claim", `{"address":"0xinvalid"}`)
+
+	for outcome, want := range map[string]float64{outcomeFunded: 1, outcomeRateLimited: 1, outcomeInvalid: 1} {
+		if got := testutil.ToFloat64(claimsTotal.WithLabelValues("metrics", outcome)); got != want {
+			t.Errorf("claims %s = %v, want %v", outcome, got, want)
+		}
+	}
+	if got := testutil.ToFloat64(limiterChecks.WithLabelValues("metrics", "limited")); got != 1 {
+		t.Errorf("limited checks = %v, want 1", got)
+	}
+
+	rec := serve("GET", "/metrics", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `faucet_claims_total{network="metrics",outcome="funded"} 1`) {
+		t.Errorf("GET /metrics = %d, want the claim counters", rec.Code)
+	}
+}
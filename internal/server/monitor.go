@@ -0,0 +1,281 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/chainflag/eth-faucet/internal/chain"
+)
+
+const (
+	monitorInterval = time.Minute
+	// rateWindow is how far back balance samples are kept to estimate the spend rate
+	rateWindow     = time.Hour
+	webhookTimeout = 10 * time.Second
+)
+
+type balanceSample struct {
+	at      time.Time
+	balance *big.Int
+}
+
+// balanceAlert is posted to the webhooks. The text field makes it readable in
+// chat webhooks such as Slack's.
+type balanceAlert struct {
+	Text             string `json:"text"`
+	Network          string `json:"network"`
+	Account          string `json:"account"`
+	Balance          string `json:"balance"`
+	Threshold        string `json:"threshold,omitempty"`
+	PayoutsRemaining int64  `json:"payoutsRemaining"`
+	SecondsRemaining int64  `json:"secondsRemaining,omitempty"`
+	Paused           bool   `json:"paused"`
+}
+
+type monitorLimits struct {
+	thresholds []*big.Int
+	floor      *big.Int
+}
+
+// balanceMonitor alerts when the balance drops below a threshold and pauses
+// claims below the floor. Apart from the limits, which are changed by
+// reloads, its state is only used by the monitor worker.
+type balanceMonitor struct {
+	webhooks []string
+	client   *http.Client
+
+	mutex  sync.Mutex
+	limits monitorLimits
+
+	samples []balanceSample
+	last    *big.Int
+	low     bool
+}
+
+// parseThresholds parses a comma separated list of Ether amounts, which are
+// returned in descending order.
+func parseThresholds(list string) ([]*big.Int, error) {
+	var thresholds []*big.Int
+	for _, item := range chain.SplitList(list) {
+		wei, err := chain.ParseEther(item)
+		if err != nil || wei.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid amount %q", item)
+		}
+		thresholds = append(thresholds, wei)
+	}
+	sort.Slice(thresholds, func(i, j int) bool { return thresholds[i].Cmp(thresholds[j]) > 0 })
+	return thresholds, nil
+}
+
+// parseFloor converts the floor from Ether to Wei.
+func parseFloor(floor float64) (*big.Int, error) {
+	wei, err := chain.ParseEther(strconv.FormatFloat(floor, 'f', -1, 64))
+	if err != nil {
+		return nil, fmt.Errorf("invalid amount %v", floor)
+	}
+	return wei, nil
+}
+
+// parseLimits parses the thresholds and the floor of the monitor config.
+func parseLimits(cfg MonitorConfig) (monitorLimits, error) {
+	thresholds, err := parseThresholds(cfg.Thresholds)
+	if err != nil {
+		return monitorLimits{}, err
+	}
+	floor, err := parseFloor(cfg.Floor)
+	if err != nil {
+		return monitorLimits{}, err
+	}
+	return monitorLimits{thresholds: thresholds, floor: floor}, nil
+}
+
+func newBalanceMonitor(cfg MonitorConfig) (*balanceMonitor, error) {
+	limits, err := parseLimits(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &balanceMonitor{
+		webhooks: chain.SplitList(cfg.Webhooks),
+		limits:   limits,
+		client:   &http.Client{Timeout: webhookTimeout},
+	}, nil
+}
+
+func (m *balanceMonitor) loadLimits() monitorLimits {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.limits
+}
+
+func (m *balanceMonitor) storeLimits(limits monitorLimits) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.limits = limits
+}
+
+// crossed returns the number of thresholds the balance is below.
+func (l monitorLimits) crossed(balance *big.Int) int {
+	crossed := 0
+	for _, threshold := range l.thresholds {
+		if balance.Cmp(threshold) < 0 {
+			crossed++
+		}
+	}
+	return crossed
+}
+
+// record adds a balance sample and returns the estimated time until the
+// faucet is empty, or 0 if the balance is not decreasing.
+func (m *balanceMonitor) record(now time.Time, balance *big.Int) time.Duration {
+	// A refill starts a new estimate
+	if n := len(m.samples); n > 0 && balance.Cmp(m.samples[n-1].balance) > 0 {
+		m.samples = nil
+	}
+	m.samples = append(m.samples, balanceSample{at: now, balance: balance})
+	for len(m.samples) > 1 && now.Sub(m.samples[0].at) > rateWindow {
+		m.samples = m.samples[1:]
+	}
+
+	first := m.samples[0]
+	spent := new(big.Int).Sub(first.balance, balance)
+	elapsed := now.Sub(first.at)
+	if spent.Sign() <= 0 || elapsed <= 0 {
+		return 0
+	}
+	// balance / (spent / elapsed)
+	left := new(big.Int).Mul(balance, big.NewInt(int64(elapsed)))
+	left.Quo(left, spent)
+	if !left.IsInt64() {
+		return 0
+	}
+	return time.Duration(left.Int64())
+}
+
+func (m *balanceMonitor) notify(alert balanceAlert) {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return
+	}
+	for _, webhook := range m.webhooks {
+		resp, err := m.client.Post(webhook, "application/json", bytes.NewReader(body))
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode >= 300 {
+				err = errors.New(resp.Status)
+			}
+		}
+		if err != nil {
+			log.WithError(err).WithField("webhook", webhook).Warn("Failed to send balance alert")
+		}
+	}
+}
+
+func (s *Server) isLowBalance() bool {
+	return atomic.LoadInt32(&s.lowBalance) == 1
+}
+
+// watchBalance periodically checks the balance of the faucet account.
+func (s *Server) watchBalance() {
+	defer s.workers.Done()
+	reader, ok := s.TxBuilder.(chain.BalanceReader)
+	if !ok {
+		return
+	}
+
+	s.checkBalance(reader)
+	ticker := time.NewTicker(monitorInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.checkBalance(reader)
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+func (s *Server) checkBalance(reader chain.BalanceReader) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	balance, err := reader.Balance(ctx)
+	if err != nil {
+		log.WithError(err).Warn("Failed to check the faucet balance")
+		return
+	}
+	balanceEther.WithLabelValues(s.networkLabel()).Set(chain.WeiToEther(balance))
+
+	m := s.monitor
+	left := m.record(time.Now(), balance)
+	alert := balanceAlert{
+		Network:          s.cfg.Network(),
+		Account:          s.Sender().Hex(),
+		Balance:          formatEther(balance),
+		SecondsRemaining: int64(left.Seconds()),
+	}
+	if payout := s.publicClass().payoutWei(); payout.Sign() > 0 {
+		alert.PayoutsRemaining = new(big.Int).Quo(balance, payout).Int64()
+	}
+
+	// The thresholds crossed before are counted with the current limits, which
+	// may have been changed by a reload since
+	limits := m.loadLimits()
+	crossed, previous := limits.crossed(balance), 0
+	if m.last != nil {
+		previous = limits.crossed(m.last)
+	}
+	m.last = balance
+
+	// Crossing the floor supersedes any threshold alert
+	low := limits.floor.Sign() > 0 && balance.Cmp(limits.floor) < 0
+	if low != m.low {
+		m.low = low
+		var value int32
+		if low {
+			value = 1
+		}
+		atomic.StoreInt32(&s.lowBalance, value)
+		alert.Paused = low
+		alert.Threshold = formatEther(limits.floor)
+		if low {
+			alert.Text = fmt.Sprintf("%s faucet %s is below the floor of %s ETH with %s ETH left, claims are paused", alert.Network, alert.Account, alert.Threshold, alert.Balance)
+			log.WithField("balance", alert.Balance).Error("Faucet balance is below the floor, pausing claims")
+		} else {
+			alert.Text = fmt.Sprintf("%s faucet %s has been refilled to %s ETH, claims are resumed", alert.Network, alert.Account, alert.Balance)
+			log.WithField("balance", alert.Balance).Warn("Faucet balance is above the floor, resuming claims")
+		}
+		m.notify(alert)
+		return
+	}
+
+	// Each threshold alerts once until a refill brings the balance above it again
+	if crossed > previous {
+		alert.Paused = low
+		alert.Threshold = formatEther(limits.thresholds[crossed-1])
+		alert.Text = fmt.Sprintf("%s faucet %s is below %s ETH with %s ETH left, about %d payouts", alert.Network, alert.Account, alert.Threshold, alert.Balance, alert.PayoutsRemaining)
+		if left > 0 {
+			alert.Text += fmt.Sprintf(" or %s at the current rate", left.Round(time.Minute))
+		}
+		log.WithFields(log.Fields{
+			"balance":   alert.Balance,
+			"threshold": alert.Threshold,
+		}).Warn("Faucet balance is low")
+		m.notify(alert)
+	}
+}
+
+func formatEther(wei *big.Int) string {
+	return strconv.FormatFloat(chain.WeiToEther(wei), 'f', -1, 64)
+}
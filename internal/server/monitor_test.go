@@ -0,0 +1,127 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chainflag/eth-faucet/internal/chain"
+)
+
+type mockBalanceReader struct {
+	balance *big.Int
+}
+
+func (m *mockBalanceReader) Balance(context.Context) (*big.Int, error) {
+	return m.balance, nil
+}
+
+type alertSink struct {
+	mutex  sync.Mutex
+	alerts []balanceAlert
+}
+
+func (a *alertSink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var alert balanceAlert
+	if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.alerts = append(a.alerts, alert)
+}
+
+func (a *alertSink) received() []balanceAlert {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return append([]balanceAlert(nil), a.alerts...)
+}
+
+func TestBalanceMonitor(t *testing.T) {
+	sink := &alertSink{}
+	hook := httptest.NewServer(sink)
+	defer hook.Close()
+
+	cfg := testConfig()
+	cfg.Monitor = MonitorConfig{Webhooks: hook.URL, Thresholds: "5, 10", Floor: 2}
+	builder := &mockTxBuilder{}
+	s := newTestServer(t, builder, cfg)
+	reader := &mockBalanceReader{}
+	check := func(ether string) {
+		reader.balance, _ = chain.ParseEther(ether)
+		s.checkBalance(reader)
+	}
+
+	check("20")
+	check("9")
+	check("8")
+	alerts := sink.received()
+	if len(alerts) != 1 || alerts[0].Threshold != "10" || alerts[0].Balance != "9" || alerts[0].PayoutsRemaining != 9 {
+		t.Fatalf("alerts = %+v, want one for the 10 ETH threshold", alerts)
+	}
+
+	check("1")
+	alerts = sink.received()
+	if len(alerts) != 2 || !alerts[1].Paused || alerts[1].Text == "" {
+		t.Fatalf("alerts = %+v, want a pause alert", alerts)
+	}
+	_, _, err := s.claim(context.Background(), "0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B")
+	if err == nil || err.Code != ErrFaucetEmpty {
+		t.Errorf("claim() error = %v, want %s", err, ErrFaucetEmpty)
+	}
+	s.queue.push(&queueItem{Address: "0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B", Class: publicClassName, Amount: chain.EtherToWei(1)})
+	s.consumeQueue()
+	if sent := builder.sent(); len(sent) != 0 {
+		t.Errorf("queued claims were sent below the floor: %v", sent)
+	}
+
+	// A refill resumes claims and re-arms the thresholds
+	check("50")
+	check("4")
+	alerts = sink.received()
+	if len(alerts) != 4 || alerts[2].Paused || alerts[3].Threshold != "5" {
+		t.Fatalf("alerts = %+v, want a resume alert and one for the 5 ETH threshold", alerts)
+	}
+	if s.isLowBalance() {
+		t.Error("claims are still paused after the refill")
+	}
+	s.consumeQueue()
+	if sent := builder.sent(); len(sent) != 1 {
+		t.Errorf("sent = %v after the refill, want the held claim", sent)
+	}
+}
+
+func TestBalanceMonitorEstimate(t *testing.T) {
+	m, err := newBalanceMonitor(MonitorConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	ether := func(n int64) *big.Int {
+		return new(big.Int).Mul(big.NewInt(n), big.NewInt(1e18))
+	}
+
+	if left := m.record(start, ether(100)); left != 0 {
+		t.Errorf("record() = %v with one sample, want 0", left)
+	}
+	if left := m.record(start.Add(10*time.Minute), ether(90)); left != 90*time.Minute {
+		t.Errorf("record() = %v, want 90m at 1 ETH per minute", left)
+	}
+	if left := m.record(start.Add(20*time.Minute), ether(200)); left != 0 {
+		t.Errorf("record() = %v after a refill, want 0", left)
+	}
+}
+
+func TestNewServerInvalidMonitor(t *testing.T) {
+	cfg := testConfig()
+	cfg.Monitor.Thresholds = "10,-1"
+	if _, err := NewServer(&mockTxBuilder{}, cfg); err == nil {
+		t.Error("NewServer() succeeded with an invalid threshold")
+	}
+}
@@ -0,0 +1,306 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"sync"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+type queueItem struct {
+	ID      string   `json:"id"`
+	Address string   `json:"address"`
+	Class   string   `json:"class"`
+	Amount  *big.Int `json:"amount"`
+	Batch   string   `json:"batch,omitempty"`
+	// ClientIPHash and Identity are written to the audit log
+	ClientIPHash string `json:"clientIpHash,omitempty"`
+	Identity     string `json:"identity,omitempty"`
+	// RequestID and Trace carry the request ID and the trace context of the
+	// claim to the transfer
+	RequestID string            `json:"requestId,omitempty"`
+	Trace     map[string]string `json:"trace,omitempty"`
+}
+
+type lane struct {
+	name    string
+	weight  int
+	current int
+	items   []*queueItem
+}
+
+// claimQueue holds one FIFO lane per claim class. Lanes are served with smooth
+// weighted round-robin so that priority classes go first without starving the
+// public lane.
+type claimQueue struct {
+	mutex sync.Mutex
+	lanes []*lane
+	// capacity applies to each lane, so that a full public lane does not turn
+	// away priority claimants
+	capacity int
+	size     int
+}
+
+func newClaimQueue(capacity int, classes []*ClaimClass) *claimQueue {
+	q := &claimQueue{capacity: capacity}
+	for _, class := range classes {
+		q.lanes = append(q.lanes, &lane{name: class.Name, weight: class.Weight})
+	}
+	return q
+}
+
+// setLanes adds the lanes of new classes and updates the weights of existing
+// ones. Lanes of removed classes are kept until their items are sent.
+func (q *claimQueue) setLanes(classes []*ClaimClass) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	lanes := make([]*lane, 0, len(classes))
+	for _, class := range classes {
+		l := q.lane(class.Name)
+		if l == nil {
+			l = &lane{name: class.Name}
+		}
+		l.weight = class.Weight
+		lanes = append(lanes, l)
+	}
+	for _, l := range q.lanes {
+		if len(l.items) > 0 && !containsLane(lanes, l.name) {
+			lanes = append(lanes, l)
+		}
+	}
+	q.lanes = lanes
+}
+
+func containsLane(lanes []*lane, name string) bool {
+	for _, l := range lanes {
+		if l.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *claimQueue) lane(name string) *lane {
+	for _, l := range q.lanes {
+		if l.name == name {
+			return l
+		}
+	}
+	return nil
+}
+
+// push appends the item to the lane of its class. It reports false when that
+// lane is at capacity.
+func (q *claimQueue) push(item *queueItem) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	l := q.lane(item.Class)
+	if l == nil || len(l.items) >= q.capacity {
+		return false
+	}
+	l.items = append(l.items, item)
+	q.size++
+	return true
+}
+
+// pushAll appends all items to the lane of their class, or none of them if
+// the lane does not have room for all of them.
+func (q *claimQueue) pushAll(class string, items []*queueItem) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	l := q.lane(class)
+	if l == nil || len(l.items)+len(items) > q.capacity {
+		return false
+	}
+	l.items = append(l.items, items...)
+	q.size += len(items)
+	return true
+}
+
+func (q *claimQueue) pop() (*queueItem, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	var best *lane
+	total := 0
+	for _, l := range q.lanes {
+		if len(l.items) == 0 {
+			continue
+		}
+		l.current += l.weight
+		total += l.weight
+		if best == nil || l.current > best.current {
+			best = l
+		}
+	}
+	if best == nil {
+		return nil, false
+	}
+
+	best.current -= total
+	item := best.items[0]
+	best.items[0] = nil
+	best.items = best.items[1:]
+	if len(best.items) == 0 {
+		best.current = 0
+	}
+	q.size--
+	return item, true
+}
+
+// popBatch removes up to limit items of the batch from the head of a lane.
+// Items of a batch are pushed together, so they are next to each other.
+func (q *claimQueue) popBatch(class, batch string, limit int) []*queueItem {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	l := q.lane(class)
+	if l == nil {
+		return nil
+	}
+	n := 0
+	for n < len(l.items) && n < limit && l.items[n].Batch == batch {
+		n++
+	}
+	items := append([]*queueItem(nil), l.items[:n]...)
+	l.items = l.items[n:]
+	q.size -= n
+	return items
+}
+
+// fullLanes returns the names of the lanes that are at capacity.
+func (q *claimQueue) fullLanes() []string {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	var full []string
+	for _, l := range q.lanes {
+		if len(l.items) >= q.capacity {
+			full = append(full, l.name)
+		}
+	}
+	return full
+}
+
+func (q *claimQueue) len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.size
+}
+
+// list returns a snapshot of the queued items in lane order.
+func (q *claimQueue) list() []queueItem {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	items := make([]queueItem, 0, q.size)
+	for _, l := range q.lanes {
+		for _, item := range l.items {
+			items = append(items, *item)
+		}
+	}
+	return items
+}
+
+// position returns the 1-based position of the item within its lane.
+func (q *claimQueue) position(id string) (int, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for _, l := range q.lanes {
+		for i, item := range l.items {
+			if item.ID == id {
+				return i + 1, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func (q *claimQueue) remove(id string) (*queueItem, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for _, l := range q.lanes {
+		for i, item := range l.items {
+			if item.ID == id {
+				l.items = append(l.items[:i], l.items[i+1:]...)
+				q.size--
+				return item, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// drain removes and returns every queued item in lane order.
+func (q *claimQueue) drain() []*queueItem {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	items := make([]*queueItem, 0, q.size)
+	for _, l := range q.lanes {
+		items = append(items, l.items...)
+		l.items = nil
+		l.current = 0
+	}
+	q.size = 0
+	return items
+}
+
+// persistQueue writes the items still waiting in the queue to the queue file
+// so that they can be picked up by the next run.
+func (s *Server) persistQueue() error {
+	pending := s.queue.drain()
+	if len(pending) == 0 {
+		return nil
+	}
+	if s.cfg.QueueFile == "" {
+		log.WithField("count", len(pending)).Warn("Dropping queued transactions since no queue file is configured")
+		return nil
+	}
+
+	data, err := json.Marshal(pending)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.cfg.QueueFile, data, 0600); err != nil {
+		return err
+	}
+	log.WithField("count", len(pending)).Info("Persisted queued transactions")
+	return nil
+}
+
+// restoreQueue loads items persisted by a previous run back into the queue.
+func (s *Server) restoreQueue() error {
+	if s.cfg.QueueFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(s.cfg.QueueFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var pending []*queueItem
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return err
+	}
+	dropped := 0
+	for _, item := range pending {
+		if item.ID == "" {
+			item.ID = uuid.NewString()
+		}
+		if s.queue.lane(item.Class) == nil {
+			item.Class = publicClassName
+		}
+		if !s.queue.push(item) {
+			dropped++
+			continue
+		}
+		s.tracker.report(item, statusQueued, "", nil)
+	}
+	if dropped > 0 {
+		log.WithField("count", dropped).Warn("Dropping persisted transactions beyond queue capacity")
+	}
+	log.WithField("count", len(pending)-dropped).Info("Restored queued transactions")
+	return os.Remove(s.cfg.QueueFile)
+}
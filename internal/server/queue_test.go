@@ -0,0 +1,53 @@
+package server
+
+import (
+	"testing"
+)
+
+func TestClaimQueuePriority(t *testing.T) {
+	classes := []*ClaimClass{
+		{Name: "internal", Weight: 3},
+		{Name: publicClassName, Weight: 1},
+	}
+	q := newClaimQueue(10, classes)
+	for i := 0; i < 4; i++ {
+		q.push(&queueItem{Class: publicClassName})
+		q.push(&queueItem{Class: "internal"})
+	}
+
+	var got []string
+	for item, ok := q.pop(); ok; item, ok = q.pop() {
+		got = append(got, item.Class)
+	}
+	want := []string{"internal", "internal", "public", "internal", "internal", "public", "public", "public"}
+	if len(got) != len(want) {
+		t.Fatalf("pop() returned %d items, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("pop() order = %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+func TestClaimQueueCapacity(t *testing.T) {
+	q := newClaimQueue(1, []*ClaimClass{{Name: "internal", Weight: 4}, {Name: publicClassName, Weight: 1}})
+	tests := []struct {
+		name  string
+		class string
+		want  bool
+	}{
+		{name: "public", class: publicClassName, want: true},
+		{name: "public full", class: publicClassName, want: false},
+		{name: "priority not blocked", class: "internal", want: true},
+		{name: "unknown class", class: "unknown", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := q.push(&queueItem{Class: tt.class}); got != tt.want {
+				t.Errorf("push() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
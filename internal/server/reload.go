@@ -0,0 +1,161 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type configChange struct {
+	key       string
+	current   string
+	requested string
+	secret    bool
+}
+
+// staticChanges lists the settings that only take effect at startup and
+// differ between the running and the requested config.
+func staticChanges(current, next *Config) []configChange {
+	changes := []configChange{
+		{key: "httpaddr", current: current.HTTPAddr, requested: next.HTTPAddr},
+		{key: "httpport", current: strconv.Itoa(current.HTTPPort), requested: strconv.Itoa(next.HTTPPort)},
+		{key: "proxycount", current: strconv.Itoa(current.ProxyCount), requested: strconv.Itoa(next.ProxyCount)},
+		{key: "queuecap", current: strconv.Itoa(current.QueueCap), requested: strconv.Itoa(next.QueueCap)},
+		{key: "queuefile", current: current.QueueFile, requested: next.QueueFile},
+		{key: "shutdowntimeout", current: strconv.Itoa(current.ShutdownTimeout), requested: strconv.Itoa(next.ShutdownTimeout)},
+		{key: "wallet.keyjson", current: current.Wallet.KeyJSON, requested: next.Wallet.KeyJSON},
+		{key: "wallet.keypass", current: current.Wallet.KeyPass, requested: next.Wallet.KeyPass},
+		{key: "wallet.privkey", current: current.Wallet.PrivKey, requested: next.Wallet.PrivKey, secret: true},
+		{key: "wallet.provider", current: current.Wallet.Provider, requested: next.Wallet.Provider},
+		{key: "wallet.chainid", current: strconv.FormatInt(current.Wallet.ChainID, 10), requested: strconv.FormatInt(next.Wallet.ChainID, 10)},
+		{key: "apikey.file", current: current.APIKey.File, requested: next.APIKey.File},
+		{key: "admin.addr", current: current.Admin.Addr, requested: next.Admin.Addr},
+		{key: "admin.port", current: strconv.Itoa(current.Admin.Port), requested: strconv.Itoa(next.Admin.Port)},
+		{key: "admin.token", current: current.Admin.Token, requested: next.Admin.Token, secret: true},
+		{key: "feed.redact", current: current.Feed.Redact, requested: strings.ToLower(next.Feed.Redact)},
+		{key: "grpc.addr", current: current.GRPC.Addr, requested: next.GRPC.Addr},
+		{key: "grpc.port", current: strconv.Itoa(current.GRPC.Port), requested: strconv.Itoa(next.GRPC.Port)},
+		{key: "metrics.addr", current: current.Metrics.Addr, requested: next.Metrics.Addr},
+		{key: "metrics.port", current: strconv.Itoa(current.Metrics.Port), requested: strconv.Itoa(next.Metrics.Port)},
+		{key: "monitor.webhooks", current: current.Monitor.Webhooks, requested: next.Monitor.Webhooks, secret: true},
+		{key: "tracing.endpoint", current: current.Tracing.Endpoint, requested: next.Tracing.Endpoint},
+		{key: "tracing.ratio", current: fmt.Sprint(current.Tracing.Ratio), requested: fmt.Sprint(next.Tracing.Ratio)},
+		{key: "audit.file", current: current.Audit.File, requested: next.Audit.File},
+		{key: "audit.salt", current: current.Audit.Salt, requested: next.Audit.Salt, secret: true},
+		{key: "stats.file", current: current.Stats.File, requested: next.Stats.File},
+		{key: "tls", current: fmt.Sprint(current.TLS), requested: fmt.Sprint(next.TLS)},
+		{key: "http", current: fmt.Sprint(current.HTTP), requested: fmt.Sprint(next.HTTP)},
+		{key: "log.format", current: strings.ToLower(current.Log.Format), requested: strings.ToLower(next.Log.Format)},
+		{key: "chains", current: fmt.Sprint(current.Chains), requested: fmt.Sprint(next.Chains)},
+		{key: "networks", current: networkNames(current), requested: networkNames(next)},
+	}
+
+	var changed []configChange
+	for _, change := range changes {
+		if change.current != change.requested {
+			changed = append(changed, change)
+		}
+	}
+	return changed
+}
+
+func networkNames(c *Config) string {
+	names := make([]string, 0, len(c.Networks))
+	for _, n := range c.Networks {
+		names = append(names, n.Name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// Reload applies the live settings of a new config to every network: the
+// public payout and interval, the network name, the claim classes and the
+// balance thresholds and floor, as well as the log level of the process. A
+// config that changes a setting which only takes effect at startup is
+// rejected as a whole.
+func (s *Server) Reload(next *Config) error {
+	changes := staticChanges(s.cfg, next)
+	configs := map[*Server]*Config{s: next}
+	if len(changes) == 0 {
+		for _, n := range next.Networks {
+			for _, network := range s.networks {
+				if network.network != n.Name {
+					continue
+				}
+				configs[network] = next.ForNetwork(n)
+				for _, change := range staticChanges(network.cfg, configs[network]) {
+					change.key = "networks." + n.Name + "." + change.key
+					changes = append(changes, change)
+				}
+			}
+		}
+	}
+	if len(changes) > 0 {
+		keys := make([]string, 0, len(changes))
+		for _, change := range changes {
+			current, requested := change.current, change.requested
+			if change.secret {
+				current, requested = "[redacted]", "[redacted]"
+			}
+			log.WithFields(log.Fields{
+				"key":       change.key,
+				"current":   current,
+				"requested": requested,
+			}).Warn("Setting cannot be changed without a restart")
+			keys = append(keys, change.key)
+		}
+		return fmt.Errorf("config reload rejected, restart required to change %s", strings.Join(keys, ", "))
+	}
+	limits := make(map[*Server]monitorLimits, len(configs))
+	for network, cfg := range configs {
+		l, err := parseLimits(cfg.Monitor)
+		if err != nil {
+			return fmt.Errorf("config reload rejected: monitor: %w", err)
+		}
+		limits[network] = l
+	}
+
+	if level, err := log.ParseLevel(next.Log.Level); err == nil && level != log.GetLevel() {
+		log.SetLevel(level)
+		log.WithField("level", level).Info("Changed log level")
+	}
+	for _, network := range s.allNetworks() {
+		if cfg, ok := configs[network]; ok {
+			network.apply(cfg, limits[network])
+		}
+	}
+	return nil
+}
+
+func (s *Server) apply(next *Config, limits monitorLimits) {
+	classes := append(resolveClasses(s.cfg, next.Faucet.Classes), s.publicClass())
+	s.cfg.mutex.Lock()
+	s.cfg.Faucet.Amount = next.Faucet.Amount
+	s.cfg.Faucet.Minutes = next.Faucet.Minutes
+	s.cfg.Faucet.Name = next.Faucet.Name
+	s.cfg.Faucet.ClassesFile = next.Faucet.ClassesFile
+	s.cfg.Faucet.Classes = next.Faucet.Classes
+	s.cfg.Monitor.Thresholds = next.Monitor.Thresholds
+	s.cfg.Monitor.Floor = next.Monitor.Floor
+	s.cfg.mutex.Unlock()
+	s.limiter.SetTTL(time.Duration(next.Faucet.Minutes) * time.Minute)
+	s.monitor.storeLimits(limits)
+
+	s.queue.setLanes(classes)
+	s.classMutex.Lock()
+	s.classes = classes
+	s.classMutex.Unlock()
+
+	log.WithFields(log.Fields{
+		"network":  s.network,
+		"payout":   next.Faucet.Amount,
+		"interval": next.Faucet.Minutes,
+		"name":     next.Faucet.Name,
+		"classes":  len(classes) - 1,
+		"floor":    next.Monitor.Floor,
+	}).Info("Reloaded config")
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/chainflag/eth-faucet/internal/chain"
+)
+
+func TestReload(t *testing.T) {
+	const address = "0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B"
+	s := newTestServer(t, &mockTxBuilder{}, testConfig())
+
+	next := testConfig()
+	next.Faucet.Amount = 5
+	next.Faucet.Minutes = 30
+	next.Faucet.Name = "sepolia"
+	next.Faucet.Classes = []ClaimClass{{Name: "Partners", Weight: 8, Addresses: []string{address}}}
+	if err := s.Reload(next); err != nil {
+		t.Fatal(err)
+	}
+	if s.cfg.Payout() != 5 || s.cfg.Interval() != 30 || s.cfg.Network() != "sepolia" {
+		t.Errorf("config = %d/%d/%s, want 5/30/sepolia", s.cfg.Payout(), s.cfg.Interval(), s.cfg.Network())
+	}
+	if class := s.classify(context.Background(), address); class.Name != "partners" {
+		t.Errorf("class = %s, want partners", class.Name)
+	}
+	if !s.queue.push(&queueItem{ID: "1", Class: "partners"}) {
+		t.Error("queue has no lane for the new class")
+	}
+
+	// Removing the class keeps its lane until the queued claim is sent
+	next = testConfig()
+	if err := s.Reload(next); err != nil {
+		t.Fatal(err)
+	}
+	if class := s.classify(context.Background(), address); class.Name != publicClassName {
+		t.Errorf("class = %s, want %s", class.Name, publicClassName)
+	}
+	if item, ok := s.queue.pop(); !ok || item.ID != "1" {
+		t.Errorf("pop = %v, %v, want the queued claim", item, ok)
+	}
+
+	next = testConfig()
+	next.Faucet.Amount = 7
+	next.HTTPPort = 9090
+	next.Admin.Token = "changed"
+	if err := s.Reload(next); err == nil {
+		t.Fatal("reload with changed ports succeeded")
+	}
+	if s.cfg.Payout() != 1 {
+		t.Errorf("payout = %d, want the rejected reload not to apply", s.cfg.Payout())
+	}
+}
+
+func TestReloadMonitor(t *testing.T) {
+	cfg := testConfig()
+	cfg.Monitor.Floor = 2
+	s := newTestServer(t, &mockTxBuilder{}, cfg)
+	reader := &mockBalanceReader{balance: chain.EtherToWei(3)}
+	s.checkBalance(reader)
+	if s.isLowBalance() {
+		t.Fatal("claims are paused above the floor")
+	}
+
+	next := testConfig()
+	next.Monitor.Floor = 5
+	next.Monitor.Thresholds = "10"
+	if err := s.Reload(next); err != nil {
+		t.Fatal(err)
+	}
+	s.checkBalance(reader)
+	if !s.isLowBalance() {
+		t.Error("claims are not paused below the reloaded floor")
+	}
+	if limits := s.monitor.loadLimits(); len(limits.thresholds) != 1 {
+		t.Errorf("thresholds = %v, want the reloaded one", limits.thresholds)
+	}
+
+	next = testConfig()
+	next.Monitor.Thresholds = "10,-1"
+	if err := s.Reload(next); err == nil {
+		t.Error("reload with an invalid threshold succeeded")
+	}
+}
@@ -0,0 +1,192 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	rpcVersion = "2.0"
+
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+	rpcServerError    = -32000
+
+	rpcBodyLimit = 64 * 1024
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type rpcError struct {
+	Code    int            `json:"code"`
+	Message string         `json:"message"`
+	Data    *errorResponse `json:"data,omitempty"`
+}
+
+// rpcRecorder captures the response of a REST handler serving an RPC call.
+type rpcRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *rpcRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *rpcRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.body.Write(b)
+}
+
+func (rec *rpcRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+// handleRPC serves the faucet_* methods of JSON-RPC 2.0 by dispatching each
+// call to the matching /api/v1 route of the router.
+func (s *Server) handleRPC(router http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.NotFound(w, r)
+			return
+		}
+
+		var raw json.RawMessage
+		if err := decodeJSONBodyLimit(r, &raw, rpcBodyLimit); err != nil {
+			renderJSON(w, rpcResponse{JSONRPC: rpcVersion, Error: &rpcError{Code: rpcParseError, Message: "Parse error"}, ID: json.RawMessage("null")}, http.StatusOK)
+			return
+		}
+
+		trimmed := bytes.TrimSpace(raw)
+		if len(trimmed) == 0 || trimmed[0] != '[' {
+			if resp := s.callRPC(router, r, raw); resp != nil {
+				renderJSON(w, resp, http.StatusOK)
+			} else {
+				w.WriteHeader(http.StatusNoContent)
+			}
+			return
+		}
+
+		var calls []json.RawMessage
+		if err := json.Unmarshal(raw, &calls); err != nil || len(calls) == 0 {
+			renderJSON(w, rpcResponse{JSONRPC: rpcVersion, Error: &rpcError{Code: rpcInvalidRequest, Message: "Invalid Request"}, ID: json.RawMessage("null")}, http.StatusOK)
+			return
+		}
+		responses := make([]*rpcResponse, 0, len(calls))
+		for _, call := range calls {
+			if resp := s.callRPC(router, r, call); resp != nil {
+				responses = append(responses, resp)
+			}
+		}
+		if len(responses) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		renderJSON(w, responses, http.StatusOK)
+	}
+}
+
+// callRPC executes a single call. Notifications, i.e. calls without an id,
+// are executed without a response.
+func (s *Server) callRPC(router http.Handler, r *http.Request, raw json.RawMessage) *rpcResponse {
+	var req rpcRequest
+	if err := json.Unmarshal(raw, &req); err != nil || req.JSONRPC != rpcVersion || req.Method == "" {
+		return &rpcResponse{JSONRPC: rpcVersion, Error: &rpcError{Code: rpcInvalidRequest, Message: "Invalid Request"}, ID: json.RawMessage("null")}
+	}
+	resp := &rpcResponse{JSONRPC: rpcVersion, ID: req.ID}
+	if len(req.ID) == 0 {
+		resp = nil
+	}
+	fail := func(code int, message string) *rpcResponse {
+		if resp != nil {
+			resp.Error = &rpcError{Code: code, Message: message}
+		}
+		return resp
+	}
+
+	var method, path string
+	var body []byte
+	switch req.Method {
+	case "faucet_claim":
+		address, ok := rpcParam(req.Params, "address")
+		if !ok {
+			return fail(rpcInvalidParams, "Invalid params: expected an address")
+		}
+		body, _ = json.Marshal(claimRequest{Address: address})
+		method, path = "POST", "/api/v1/claim"
+	case "faucet_info":
+		method, path = "GET", "/api/v1/info"
+	case "faucet_status":
+		id, ok := rpcParam(req.Params, "id")
+		if !ok {
+			return fail(rpcInvalidParams, "Invalid params: expected a claim id")
+		}
+		method, path = "GET", "/api/v1/claim/"+url.PathEscape(id)
+	default:
+		return fail(rpcMethodNotFound, "Method not found")
+	}
+
+	sub, err := http.NewRequestWithContext(r.Context(), method, path, bytes.NewReader(body))
+	if err != nil {
+		return fail(rpcInternalError, "Internal error")
+	}
+	sub.RemoteAddr = r.RemoteAddr
+	sub.Header = r.Header.Clone()
+	sub.Header.Set("Content-Type", "application/json")
+	rec := &rpcRecorder{header: make(http.Header)}
+	router.ServeHTTP(rec, sub)
+
+	if resp == nil {
+		return nil
+	}
+	if rec.status == http.StatusOK {
+		resp.Result = json.RawMessage(bytes.TrimSpace(rec.body.Bytes()))
+		return resp
+	}
+	var apiErr errorResponse
+	if err := json.Unmarshal(rec.body.Bytes(), &apiErr); err != nil || apiErr.Code == "" {
+		return fail(rpcInternalError, "Internal error")
+	}
+	code := rpcServerError
+	if rec.status == http.StatusBadRequest {
+		code = rpcInvalidParams
+	}
+	resp.Error = &rpcError{Code: code, Message: apiErr.Message, Data: &apiErr}
+	return resp
+}
+
+// rpcParam reads a string parameter passed either by position or by name.
+func rpcParam(params json.RawMessage, name string) (string, bool) {
+	var positional []string
+	if err := json.Unmarshal(params, &positional); err == nil && len(positional) == 1 {
+		return strings.TrimSpace(positional[0]), true
+	}
+	var named map[string]string
+	if err := json.Unmarshal(params, &named); err == nil && named[name] != "" {
+		return strings.TrimSpace(named[name]), true
+	}
+	return "", false
+}
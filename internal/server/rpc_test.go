@@ -0,0 +1,83 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/urfave/negroni"
+)
+
+func TestHandleRPC(t *testing.T) {
+	s := newTestServer(t, &mockTxBuilder{}, testConfig())
+	router := s.setupRouter()
+	call := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/rpc", strings.NewReader(body))
+		req.RemoteAddr = "192.0.2.1:1234"
+		rec := httptest.NewRecorder()
+		negroni.New(negroni.Wrap(router)).ServeHTTP(rec, req)
+		return rec
+	}
+
+	var claim struct {
+		Result claimStatusResponse `json:"result"`
+		Error  *rpcError           `json:"error"`
+	}
+	rec := call(`{"jsonrpc":"2.0","id":1,"method":"faucet_claim","params":["0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B"]}`)
+	if err := json.NewDecoder(rec.Body).Decode(&claim); err != nil || claim.Error != nil || claim.Result.ID == "" {
+		t.Fatalf("faucet_claim = %+v, error = %v", claim, err)
+	}
+
+	tests := []struct {
+		name    string
+		body    string
+		code    int
+		apiCode ErrorCode
+	}{
+		{name: "info", body: `{"jsonrpc":"2.0","id":2,"method":"faucet_info"}`},
+		{name: "status", body: `{"jsonrpc":"2.0","id":3,"method":"faucet_status","params":{"id":"` + claim.Result.ID + `"}}`},
+		{name: "unknown claim", body: `{"jsonrpc":"2.0","id":4,"method":"faucet_status","params":["unknown"]}`, code: rpcServerError, apiCode: ErrNotFound},
+		{name: "rate limited", body: `{"jsonrpc":"2.0","id":5,"method":"faucet_claim","params":{"address":"0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B"}}`, code: rpcServerError, apiCode: ErrRateLimited},
+		{name: "invalid address", body: `{"jsonrpc":"2.0","id":6,"method":"faucet_claim","params":["0xinvalid"]}`, code: rpcInvalidParams, apiCode: ErrInvalidAddress},
+		{name: "missing params", body: `{"jsonrpc":"2.0","id":7,"method":"faucet_claim"}`, code: rpcInvalidParams},
+		{name: "unknown method", body: `{"jsonrpc":"2.0","id":8,"method":"eth_chainId"}`, code: rpcMethodNotFound},
+		{name: "invalid request", body: `{"id":9,"method":"faucet_info"}`, code: rpcInvalidRequest},
+		{name: "parse error", body: `{"jsonrpc":`, code: rpcParseError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp struct {
+				JSONRPC string          `json:"jsonrpc"`
+				Result  json.RawMessage `json:"result"`
+				Error   *rpcError       `json:"error"`
+			}
+			rec := call(tt.body)
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp.JSONRPC != rpcVersion {
+				t.Fatalf("response is not JSON-RPC: %v", err)
+			}
+			if tt.code == 0 {
+				if resp.Error != nil || len(resp.Result) == 0 {
+					t.Errorf("error = %+v, want result", resp.Error)
+				}
+				return
+			}
+			if resp.Error == nil || resp.Error.Code != tt.code {
+				t.Fatalf("error = %+v, want code %d", resp.Error, tt.code)
+			}
+			if tt.apiCode != "" && (resp.Error.Data == nil || resp.Error.Data.Code != tt.apiCode) {
+				t.Errorf("error data = %+v, want %s", resp.Error.Data, tt.apiCode)
+			}
+		})
+	}
+
+	rec = call(`[{"jsonrpc":"2.0","id":1,"method":"faucet_info"},{"jsonrpc":"2.0","method":"faucet_info"},{"jsonrpc":"2.0","id":2,"method":"faucet_info"}]`)
+	var batch []rpcResponse
+	if err := json.NewDecoder(rec.Body).Decode(&batch); err != nil || len(batch) != 2 {
+		t.Errorf("batch = %+v, error = %v, want 2 responses", batch, err)
+	}
+	if rec := call(`{"jsonrpc":"2.0","method":"faucet_info"}`); rec.Code != http.StatusNoContent {
+		t.Errorf("notification status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+}
@@ -2,77 +2,620 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/LK4D4/trylock"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
 	"github.com/urfave/negroni"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
 
+	"github.com/chainflag/eth-faucet/internal/apikey"
+	"github.com/chainflag/eth-faucet/internal/audit"
 	"github.com/chainflag/eth-faucet/internal/chain"
+	"github.com/chainflag/eth-faucet/internal/history"
 	"github.com/chainflag/eth-faucet/web"
 )
 
+const chainCheckInterval = 5 * time.Minute
+
 type Server struct {
 	chain.TxBuilder
-	mutex trylock.Mutex
-	cfg   *Config
-	queue chan string
+	mutex      trylock.Mutex
+	cfg        *Config
+	classMutex sync.RWMutex
+	classes    []*ClaimClass
+	queue      *claimQueue
+	keys       *apikey.Store
+	audit      *audit.Log
+	history    *history.Store
+	limiter    *Limiter
+	tracker    *claimTracker
+	feed       *activityFeed
+	closing    chan struct{}
+	paused     int32
+	monitor    *balanceMonitor
+	lowBalance int32
+	tlsConfig  *tls.Config
+	httpServer *http.Server
+	adminSrv   *http.Server
+	metricsSrv *http.Server
+	redirect   *http.Server
+	grpcSrv    *grpc.Server
+	quit       chan struct{}
+	workers    sync.WaitGroup
+
+	shutdownOnce sync.Once
+	shutdownErr  error
+
+	// network is the route name of an additional network, and networks are
+	// the additional networks served by the default one
+	network  string
+	networks []*Server
 }
 
-func NewServer(builder chain.TxBuilder, cfg *Config) *Server {
+func NewServer(builder chain.TxBuilder, cfg *Config) (*Server, error) {
+	monitor, err := newBalanceMonitor(cfg.Monitor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid monitor config: %w", err)
+	}
+	// The public class goes last so that it loses ties against priority classes
+	classes := append(resolveClasses(cfg, cfg.Faucet.Classes), newPublicClass(cfg))
+	tracker := newClaimTracker(trackerCapacity)
+	feed := newActivityFeed(cfg.Feed.Redact)
+	tracker.onChange = feed.publishClaim
 	return &Server{
 		TxBuilder: builder,
 		cfg:       cfg,
-		queue:     make(chan string, cfg.queueCap),
+		classes:   classes,
+		queue:     newClaimQueue(cfg.QueueCap, classes),
+		limiter:   NewLimiter(cfg.ProxyCount, time.Duration(cfg.Interval())*time.Minute),
+		tracker:   tracker,
+		feed:      feed,
+		closing:   make(chan struct{}),
+		monitor:   monitor,
+		quit:      make(chan struct{}),
+	}, nil
+}
+
+// AddNetwork serves the API of another network below /api/{name}/ and
+// /api/v1/{name}/. It must be called before Start.
+func (s *Server) AddNetwork(name string, network *Server) {
+	network.network = name
+	network.limiter.network = name
+	s.networks = append(s.networks, network)
+}
+
+// lookupNetwork returns the network of the route name, or the default network
+// for an empty name.
+func (s *Server) lookupNetwork(name string) (*Server, bool) {
+	for _, network := range s.allNetworks() {
+		if network.network == name {
+			return network, true
+		}
 	}
+	return nil, false
+}
+
+// allNetworks returns the default network followed by the additional ones.
+func (s *Server) allNetworks() []*Server {
+	return append([]*Server{s}, s.networks...)
 }
 
 func (s *Server) setupRouter() *http.ServeMux {
 	router := http.NewServeMux()
 	router.Handle("/", http.FileServer(web.Dist()))
-	limiter := NewLimiter(s.cfg.proxyCount, time.Duration(s.cfg.interval)*time.Minute)
-	router.Handle("/api/claim", negroni.New(limiter, negroni.Wrap(s.handleClaim())))
+	router.Handle("/api/claim", negroni.New(negroni.HandlerFunc(s.authenticate), negroni.HandlerFunc(s.classifyClaim), s.limiter, negroni.Wrap(s.handleClaim())))
+	router.Handle("/api/claim/batch", negroni.New(negroni.HandlerFunc(s.authenticate), negroni.Wrap(s.handleBatchClaim(false))))
+	router.Handle("/api/claim/", s.handleClaimByID())
 	router.Handle("/api/info", s.handleInfo())
+	router.Handle("/api/stats", s.handleStats())
+	router.Handle("/api/ws", s.feed)
+
+	// Versioned routes share the handlers and respond with structured types
+	router.Handle("/api/v1/claim", negroni.New(negroni.HandlerFunc(s.authenticate), negroni.HandlerFunc(s.classifyClaim), s.limiter, negroni.Wrap(s.handleClaim())))
+	router.Handle("/api/v1/claim/batch", negroni.New(negroni.HandlerFunc(s.authenticate), negroni.Wrap(s.handleBatchClaim(false))))
+	router.Handle("/api/v1/claim/", s.handleClaimByID())
+	router.Handle("/api/v1/info", s.handleInfo())
+	router.Handle("/api/v1/stats", s.handleStats())
+	router.Handle("/api/v1/ws", s.feed)
+	router.Handle("/api/v1/openapi.json", handleOpenAPI())
+	// Metrics are only public if they are not served on a listener of their own
+	if !listenerEnabled(s.cfg.Metrics.Addr, s.cfg.Metrics.Port) {
+		router.Handle("/metrics", promhttp.Handler())
+	}
+	router.Handle("/healthz", s.handleHealth())
+	router.Handle("/readyz", s.handleReady())
+	router.Handle("/api/networks", s.handleNetworks())
+	router.Handle("/api/v1/networks", s.handleNetworks())
+	for _, network := range s.networks {
+		mountNetwork(router, network.network, network.setupRouter())
+	}
+
+	// JSON-RPC calls are dispatched to the versioned routes above
+	router.Handle("/api/rpc", s.handleRPC(router))
+	router.Handle("/api/v1/rpc", s.handleRPC(router))
 
 	return router
 }
 
-func (s *Server) Run() {
-	go func() {
-		ticker := time.NewTicker(time.Second)
-		for range ticker.C {
-			s.consumeQueue()
+// Start restores any persisted queue, binds the HTTP listeners and begins serving
+// requests and consuming the queue in the background.
+func (s *Server) Start(ctx context.Context) error {
+	var keys *apikey.Store
+	if s.cfg.APIKey.File != "" {
+		var err error
+		keys, err = apikey.Open(s.cfg.APIKey.File)
+		if err != nil {
+			return fmt.Errorf("failed to open API key store: %w", err)
 		}
-	}()
+	}
+	var auditLog *audit.Log
+	if s.cfg.Audit.File != "" {
+		var err error
+		auditLog, err = audit.Open(s.cfg.Audit.File, s.cfg.Audit.Salt)
+		if err != nil {
+			return fmt.Errorf("failed to open audit log: %w", err)
+		}
+	}
+	var claims *history.Store
+	if s.cfg.Stats.File != "" {
+		var err error
+		claims, err = history.Open(s.cfg.Stats.File)
+		if err != nil {
+			return fmt.Errorf("failed to open claim history: %w", err)
+		}
+	}
+	for _, network := range s.allNetworks() {
+		network.keys = keys
+		network.audit = auditLog
+		network.history = claims
+	}
+
+	tlsConfig, manager, err := newTLSConfig(s.cfg.TLS)
+	if err != nil {
+		return fmt.Errorf("failed to set up TLS: %w", err)
+	}
+	s.tlsConfig = tlsConfig
+
+	// All listeners are bound before any is served, so that a failure leaves none open
+	ln, err := listen(ctx, s.cfg.HTTPAddr, s.cfg.HTTPPort, tlsConfig)
+	if err != nil {
+		return err
+	}
+	listeners := []net.Listener{ln}
+	fail := func(err error) error {
+		for _, l := range listeners {
+			l.Close()
+		}
+		return err
+	}
+	if network, _ := listenAddress(s.cfg.HTTPAddr, s.cfg.HTTPPort); network == "unix" && s.cfg.ProxyCount == 0 {
+		log.Warn("Clients on a Unix socket share one rate limit unless proxycount is set")
+	}
+	if !listenerEnabled(s.cfg.Metrics.Addr, s.cfg.Metrics.Port) {
+		log.Warn("Metrics are served publicly on /metrics, set metrics.port or metrics.addr to serve them apart")
+	}
+	var redirectLn, adminLn, metricsLn, grpcLn net.Listener
+	if s.cfg.TLS.RedirectPort > 0 {
+		if redirectLn, err = listen(ctx, "", s.cfg.TLS.RedirectPort, nil); err != nil {
+			return fail(err)
+		}
+		listeners = append(listeners, redirectLn)
+	}
+	if listenerEnabled(s.cfg.Admin.Addr, s.cfg.Admin.Port) {
+		if s.cfg.Admin.Token == "" {
+			return fail(errors.New("admin listener requires an admin token"))
+		}
+		if adminLn, err = listen(ctx, s.cfg.Admin.Addr, s.cfg.Admin.Port, tlsConfig); err != nil {
+			return fail(err)
+		}
+		listeners = append(listeners, adminLn)
+	}
+	if listenerEnabled(s.cfg.Metrics.Addr, s.cfg.Metrics.Port) {
+		if metricsLn, err = listen(ctx, s.cfg.Metrics.Addr, s.cfg.Metrics.Port, tlsConfig); err != nil {
+			return fail(err)
+		}
+		listeners = append(listeners, metricsLn)
+	}
+	if listenerEnabled(s.cfg.GRPC.Addr, s.cfg.GRPC.Port) {
+		// gRPC does the TLS handshake itself, see newGRPCServer
+		if grpcLn, err = listen(ctx, s.cfg.GRPC.Addr, s.cfg.GRPC.Port, nil); err != nil {
+			return fail(err)
+		}
+		listeners = append(listeners, grpcLn)
+	}
+	// The queue file is removed once restored, so the queue is only restored
+	// when nothing else can fail
+	networks := s.allNetworks()
+	for i, network := range networks {
+		if err := network.restoreQueue(); err != nil {
+			for _, restored := range networks[:i] {
+				if perr := restored.persistQueue(); perr != nil {
+					log.WithError(perr).Error("Failed to persist the restored queue")
+				}
+			}
+			return fail(fmt.Errorf("failed to restore queue: %w", err))
+		}
+	}
 
-	n := negroni.New(negroni.NewRecovery(), negroni.NewLogger())
+	if redirectLn != nil {
+		// The redirect listener also answers the HTTP challenges of ACME
+		var handler http.Handler = redirectHTTPS(s.cfg.HTTPPort)
+		if manager != nil {
+			handler = manager.HTTPHandler(handler)
+		}
+		s.redirect = s.newHTTPServer(handler)
+		go serve(s.redirect, redirectLn, "redirect")
+	}
+	if adminLn != nil {
+		n := newPipeline()
+		n.UseHandler(s.setupAdminRouter())
+		s.adminSrv = s.newHTTPServer(n)
+		go serve(s.adminSrv, adminLn, "admin")
+	}
+	if metricsLn != nil {
+		n := newPipeline()
+		n.UseHandler(s.setupMetricsRouter())
+		s.metricsSrv = s.newHTTPServer(n)
+		go serve(s.metricsSrv, metricsLn, "metrics")
+	}
+	if grpcLn != nil {
+		s.grpcSrv = s.newGRPCServer()
+		go serveGRPC(s.grpcSrv, grpcLn)
+	}
+
+	n := newPipeline()
 	n.UseHandler(s.setupRouter())
-	log.Infof("Starting http server %d", s.cfg.httpPort)
-	log.Fatal(http.ListenAndServe(":"+strconv.Itoa(s.cfg.httpPort), n))
+	s.httpServer = s.newHTTPServer(n)
+
+	for _, network := range networks {
+		network.workers.Add(6)
+		go network.runQueue()
+		go network.watchClaims()
+		go network.watchChain()
+		go network.watchBalance()
+		go network.sampleMetrics()
+		go network.runFeed()
+	}
+	name := "http"
+	if tlsConfig != nil {
+		name = "https"
+	}
+	go serve(s.httpServer, ln, name)
+	return nil
+}
+
+// mountNetwork routes the API of a network below /api/{name}/ and
+// /api/v1/{name}/, or the given prefixes, to its router by removing the name
+// from the path.
+func mountNetwork(router *http.ServeMux, name string, handler http.Handler, prefixes ...string) {
+	if len(prefixes) == 0 {
+		prefixes = []string{"/api/", "/api/v1/"}
+	}
+	for _, prefix := range prefixes {
+		prefix, mount := prefix, prefix+name+"/"
+		router.Handle(mount, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sub := r.Clone(r.Context())
+			sub.URL.Path = prefix + strings.TrimPrefix(r.URL.Path, mount)
+			sub.URL.RawPath = ""
+			handler.ServeHTTP(w, sub)
+		}))
+	}
+}
+
+func serve(srv *http.Server, ln net.Listener, name string) {
+	log.Infof("Starting %s server %s", name, ln.Addr())
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.WithError(err).Errorf("The %s server stopped unexpectedly", name)
+	}
+}
+
+// Shutdown stops accepting claims, waits for in-flight requests and transfers,
+// and drains the queue until ctx expires. Whatever is left is persisted to the
+// configured queue file. Later calls wait for the first one and return its
+// result.
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.httpServer == nil {
+		return nil
+	}
+	s.shutdownOnce.Do(func() {
+		s.shutdownErr = s.shutdown(ctx)
+	})
+	return s.shutdownErr
+}
+
+func (s *Server) shutdown(ctx context.Context) error {
+	// Closing first also ends the event streams, which would otherwise keep Shutdown waiting
+	for _, network := range s.allNetworks() {
+		close(network.closing)
+	}
+	err := s.httpServer.Shutdown(ctx)
+	if s.adminSrv != nil {
+		if aerr := s.adminSrv.Shutdown(ctx); aerr != nil && err == nil {
+			err = aerr
+		}
+	}
+	if s.metricsSrv != nil {
+		if merr := s.metricsSrv.Shutdown(ctx); merr != nil && err == nil {
+			err = merr
+		}
+	}
+	if s.redirect != nil {
+		if rerr := s.redirect.Shutdown(ctx); rerr != nil && err == nil {
+			err = rerr
+		}
+	}
+	if s.grpcSrv != nil {
+		stopped := make(chan struct{})
+		go func() {
+			s.grpcSrv.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			s.grpcSrv.Stop()
+		}
+	}
+
+	// The networks drain their queues in parallel, each with its own signer
+	networks := s.allNetworks()
+	errs := make([]error, len(networks))
+	var wg sync.WaitGroup
+	for i, network := range networks {
+		wg.Add(1)
+		go func(i int, network *Server) {
+			defer wg.Done()
+			errs[i] = network.stop(ctx)
+			// The providers are only needed until the queue is drained
+			if closer, ok := network.TxBuilder.(chain.Closer); ok {
+				closer.Close()
+			}
+		}(i, network)
+	}
+	wg.Wait()
+	for _, nerr := range errs {
+		if nerr != nil && err == nil {
+			err = nerr
+		}
+	}
+	if s.audit != nil {
+		if aerr := s.audit.Close(); aerr != nil && err == nil {
+			err = aerr
+		}
+	}
+	if s.history != nil {
+		if herr := s.history.Close(); herr != nil && err == nil {
+			err = herr
+		}
+	}
+	return err
+}
+
+// stop ends the background workers, then drains and persists the queue.
+func (s *Server) stop(ctx context.Context) error {
+	close(s.quit)
+	s.workers.Wait()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	// A held queue is kept for the next run instead of being sent
+	if !s.holdsQueue() {
+		s.drainQueue(ctx)
+	}
+	if err := s.persistQueue(); err != nil {
+		return fmt.Errorf("failed to persist queue: %w", err)
+	}
+	return nil
+}
+
+func (s *Server) isClosing() bool {
+	select {
+	case <-s.closing:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *Server) isPaused() bool {
+	return atomic.LoadInt32(&s.paused) == 1
+}
+
+// holdsQueue reports whether queued transfers are held back, because the faucet
+// is paused or its balance is below the floor.
+func (s *Server) holdsQueue() bool {
+	return s.isPaused() || s.isLowBalance()
+}
+
+// acceptError returns why the faucet does not accept new claims, if it does
+// not. Queued claims are held while the faucet is paused or its balance is
+// below the floor, and otherwise drained while it is shutting down.
+func (s *Server) acceptError() *apiError {
+	switch {
+	case s.isClosing():
+		return errShuttingDown
+	case s.isPaused():
+		return errPaused
+	case s.isLowBalance():
+		return errLowBalance
+	}
+	return nil
+}
+
+// SetPaused stops or resumes accepting claims and sending queued transfers.
+func (s *Server) SetPaused(paused bool) {
+	var value int32
+	if paused {
+		value = 1
+	}
+	if atomic.SwapInt32(&s.paused, value) != value {
+		log.WithField("paused", paused).Warn("Faucet pause state changed")
+	}
+}
+
+func (s *Server) runQueue() {
+	defer s.workers.Done()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.consumeQueue()
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// watchChain periodically checks that the provider is still on the chain the
+// transfers are signed for, and pauses the faucet if it is not.
+func (s *Server) watchChain() {
+	defer s.workers.Done()
+	verifier, ok := s.TxBuilder.(chain.ChainVerifier)
+	if !ok {
+		return
+	}
+
+	ticker := time.NewTicker(chainCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.verifyChain(verifier)
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+func (s *Server) verifyChain(verifier chain.ChainVerifier) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := verifier.VerifyChainID(ctx)
+	if errors.Is(err, chain.ErrChainIDMismatch) {
+		log.WithError(err).WithField("network", s.cfg.Network()).Error("Provider switched chains, pausing the faucet")
+		s.SetPaused(true)
+	} else if err != nil {
+		log.WithError(err).Warn("Failed to verify the chain ID of the provider")
+	}
 }
 
 func (s *Server) consumeQueue() {
-	if len(s.queue) == 0 {
+	s.observeQueue()
+	if s.queue.len() == 0 || s.holdsQueue() {
 		return
 	}
 
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	for len(s.queue) != 0 {
-		address := <-s.queue
-		txHash, err := s.Transfer(context.Background(), address, chain.EtherToWei(int64(s.cfg.payout)))
-		if err != nil {
-			log.WithError(err).Error("Failed to handle transaction in the queue")
-		} else {
-			log.WithFields(log.Fields{
-				"txHash":  txHash,
-				"address": address,
-			}).Info("Consume from queue successfully")
+	s.drainQueue(context.Background())
+	s.observeQueue()
+}
+
+// drainQueue sends queued transfers until the queue is empty or ctx is done.
+// The caller must hold s.mutex.
+func (s *Server) drainQueue(ctx context.Context) {
+	for ctx.Err() == nil {
+		item, ok := s.queue.pop()
+		if !ok {
+			return
+		}
+		batcher, ok := s.TxBuilder.(chain.BatchTxBuilder)
+		if item.Batch == "" || !ok {
+			s.transferItem(ctx, item)
+			continue
+		}
+		items := append([]*queueItem{item}, s.queue.popBatch(item.Class, item.Batch, batchChunkSize-1)...)
+		s.transferBatch(ctx, batcher, items)
+	}
+}
+
+func (s *Server) transferItem(ctx context.Context, item *queueItem) {
+	ctx, span := tracer.Start(extractTrace(withRequestID(ctx, item.RequestID), item), "queue.transfer", trace.WithAttributes(attribute.String("faucet.class", item.Class)))
+	txHash, err := s.Transfer(ctx, item.Address, item.Amount)
+	s.recordPayout(item, hashHex(txHash, err), err)
+	if err != nil {
+		apiErr := transferError(err)
+		endSpan(span, apiErr)
+		s.tracker.report(item, statusFailed, "", apiErr)
+		queueTransfers.WithLabelValues(s.networkLabel(), "failed").Inc()
+		logger(ctx).WithError(err).Error("Failed to handle transaction in the queue")
+		return
+	}
+	span.End()
+	s.recordHistory(item)
+	s.tracker.report(item, statusBroadcast, txHash.Hex(), nil)
+	queueTransfers.WithLabelValues(s.networkLabel(), "sent").Inc()
+	logger(ctx).WithFields(log.Fields{
+		"txHash":  txHash,
+		"address": item.Address,
+		"class":   item.Class,
+	}).Info("Consume from queue successfully")
+}
+
+// classify picks the claim class of a request by its API key or address,
+// falling back to the public class.
+func (s *Server) classify(ctx context.Context, address string) *ClaimClass {
+	if class := s.classifyKey(apiKeyFromContext(ctx)); class != nil {
+		return class
+	}
+	account := common.HexToAddress(address)
+	for _, class := range s.claimClasses() {
+		if class.members[account] {
+			return class
 		}
 	}
+	return s.publicClass()
+}
+
+func (s *Server) classifyKey(key *apikey.Key) *ClaimClass {
+	if key == nil || key.Class == "" {
+		return nil
+	}
+	for _, class := range s.claimClasses() {
+		if class.Name == strings.ToLower(key.Class) {
+			return class
+		}
+	}
+	return nil
+}
+
+func (s *Server) publicClass() *ClaimClass {
+	classes := s.claimClasses()
+	return classes[len(classes)-1]
+}
+
+// claimClasses returns the current classes, which are replaced as a whole
+// when the config is reloaded.
+func (s *Server) claimClasses() []*ClaimClass {
+	s.classMutex.RLock()
+	defer s.classMutex.RUnlock()
+	return s.classes
+}
+
+func (s *Server) classifyClaim(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	address, err := readAddress(r)
+	if err != nil {
+		s.countClaim(outcomeInvalid)
+		renderReadError(w, r, err)
+		return
+	}
+	next(w, r.WithContext(withClaimClass(r.Context(), s.classify(r.Context(), address))))
 }
 
 func (s *Server) handleClaim() http.HandlerFunc {
@@ -84,38 +627,132 @@ func (s *Server) handleClaim() http.HandlerFunc {
 
 		// The error always be nil since it has already been handled in limiter
 		address, _ := readAddress(r)
-		// Try to lock mutex if the work queue is empty
-		if len(s.queue) != 0 || !s.mutex.TryLock() {
-			select {
-			case s.queue <- address:
-				log.WithFields(log.Fields{
-					"address": address,
-				}).Info("Added to queue successfully")
-				resp := claimResponse{Message: fmt.Sprintf("Added %s to the queue", address)}
-				renderJSON(w, resp, http.StatusOK)
-			default:
-				log.Warn("Max queue capacity reached")
-				renderJSON(w, claimResponse{Message: "Faucet queue is too long, please try again later"}, http.StatusServiceUnavailable)
-			}
+		item, message, err := s.claim(r.Context(), address)
+		if err != nil {
+			renderError(w, r, err)
 			return
 		}
+		s.renderClaim(w, r, item.ID, message)
+	}
+}
 
-		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
-		defer cancel()
-		txHash, err := s.Transfer(ctx, address, chain.EtherToWei(int64(s.cfg.payout)))
-		s.mutex.Unlock()
-		if err != nil {
-			log.WithError(err).Error("Failed to send transaction")
-			renderJSON(w, claimResponse{Message: err.Error()}, http.StatusInternalServerError)
-			return
+// claim queues a transfer to the address or funds it directly if the queue is
+// empty. It is shared by every API, which authenticate and rate limit the
+// claim beforehand.
+func (s *Server) claim(ctx context.Context, address string) (_ *queueItem, _ string, apiErr *apiError) {
+	defer func() {
+		if apiErr != nil {
+			s.countClaim(claimOutcome(apiErr))
 		}
+	}()
+	if err := s.acceptError(); err != nil {
+		return nil, "", err
+	}
 
-		log.WithFields(log.Fields{
-			"txHash":  txHash,
+	class := claimClassFromContext(ctx)
+	if class == nil {
+		class = s.classify(ctx, address)
+	}
+	amount := class.payoutWei()
+	if key := apiKeyFromContext(ctx); key != nil && key.Payout > 0 {
+		amount = chain.EtherToWei(int64(key.Payout))
+	}
+	item := &queueItem{
+		ID:           uuid.NewString(),
+		Address:      address,
+		Class:        class.Name,
+		Amount:       amount,
+		ClientIPHash: s.hashClientIP(clientIPFromContext(ctx)),
+		Identity:     claimIdentity(ctx),
+		RequestID:    requestIDFromContext(ctx),
+	}
+	// Try to lock mutex if the work queue is empty
+	if s.queue.len() != 0 || !s.mutex.TryLock() {
+		ctx, span := tracer.Start(ctx, "queue.enqueue", trace.WithAttributes(attribute.String("faucet.class", class.Name)))
+		item.Trace = injectTrace(ctx)
+		if !s.queue.push(item) {
+			endSpan(span, errQueueFull)
+			logger(ctx).WithField("class", class.Name).Warn("Max queue capacity reached")
+			return nil, "", errQueueFull
+		}
+		span.End()
+		s.tracker.report(item, statusQueued, "", nil)
+		s.countClaim(outcomeQueued)
+		s.observeQueue()
+		logger(ctx).WithFields(log.Fields{
 			"address": address,
-		}).Info("Funded directly successfully")
-		resp := claimResponse{Message: fmt.Sprintf("Txhash: %s", txHash)}
-		renderJSON(w, resp, http.StatusOK)
+			"class":   class.Name,
+		}).Info("Added to queue successfully")
+		return item, fmt.Sprintf("Added %s to the queue", address), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	txHash, err := s.Transfer(ctx, address, amount)
+	s.mutex.Unlock()
+	s.recordPayout(item, hashHex(txHash, err), err)
+	if err != nil {
+		apiErr := transferError(err)
+		s.tracker.report(item, statusFailed, "", apiErr)
+		logger(ctx).WithError(err).Error("Failed to send transaction")
+		return nil, "", apiErr
+	}
+
+	s.recordHistory(item)
+	s.tracker.report(item, statusBroadcast, txHash.Hex(), nil)
+	s.countClaim(outcomeFunded)
+	logger(ctx).WithFields(log.Fields{
+		"txHash":  txHash,
+		"address": address,
+		"class":   class.Name,
+	}).Info("Funded directly successfully")
+	return item, fmt.Sprintf("Txhash: %s", txHash), nil
+}
+
+// renderClaim renders an accepted claim, as a message on legacy routes and as
+// its status on /api/v1 routes.
+func (s *Server) renderClaim(w http.ResponseWriter, r *http.Request, id, message string) {
+	if !isV1(r) {
+		renderJSON(w, claimResponse{Message: message, ID: id}, http.StatusOK)
+		return
+	}
+	record, _ := s.tracker.get(id)
+	renderJSON(w, s.claimStatus(record), http.StatusOK)
+}
+
+func (s *Server) claimStatus(record claimRecord) claimStatusResponse {
+	position, queued := s.queue.position(record.ID)
+	return claimStatusResponse{
+		ID:          record.ID,
+		Status:      record.Status,
+		TxHash:      record.TxHash,
+		BlockNumber: record.BlockNumber,
+		Queued:      queued,
+		Position:    position,
+		Code:        record.Code,
+		Error:       record.Error,
+	}
+}
+
+func (s *Server) handleNetworks() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			http.NotFound(w, r)
+			return
+		}
+		networks := make([]networkResponse, 0, len(s.networks)+1)
+		for _, network := range s.allNetworks() {
+			path := "/api"
+			if network.network != "" {
+				path += "/" + network.network
+			}
+			networks = append(networks, networkResponse{
+				ID:      network.network,
+				Network: network.cfg.Network(),
+				Path:    path,
+			})
+		}
+		renderJSON(w, networks, http.StatusOK)
 	}
 }
 
@@ -127,8 +764,8 @@ func (s *Server) handleInfo() http.HandlerFunc {
 		}
 		renderJSON(w, infoResponse{
 			Account: s.Sender().String(),
-			Network: s.cfg.network,
-			Payout:  strconv.Itoa(s.cfg.payout),
+			Network: s.cfg.Network(),
+			Payout:  strconv.Itoa(s.cfg.Payout()),
 		}, http.StatusOK)
 	}
 }
@@ -0,0 +1,299 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/urfave/negroni"
+
+	"github.com/chainflag/eth-faucet/internal/chain"
+)
+
+type mockTxBuilder struct {
+	mutex     sync.Mutex
+	transfers []string
+}
+
+func (m *mockTxBuilder) Sender() common.Address {
+	return common.HexToAddress("0x7ef5a6135f1fd6a02593eedc869c6d41d934aef8")
+}
+
+func (m *mockTxBuilder) Transfer(_ context.Context, to string, _ *big.Int) (common.Hash, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.transfers = append(m.transfers, to)
+	return common.BytesToHash([]byte(to)), nil
+}
+
+func testConfig() *Config {
+	cfg := DefaultConfig()
+	cfg.HTTPPort = 0
+	cfg.QueueCap = 10
+	return cfg
+}
+
+func newTestServer(t *testing.T, builder chain.TxBuilder, cfg *Config) *Server {
+	t.Helper()
+	s, err := NewServer(builder, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func (m *mockTxBuilder) sent() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]string(nil), m.transfers...)
+}
+
+func TestShutdownDrainsQueue(t *testing.T) {
+	builder := &mockTxBuilder{}
+	s := newTestServer(t, builder, testConfig())
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	s.queue.push(&queueItem{Address: "0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B", Class: publicClassName})
+	s.queue.push(&queueItem{Address: "0x7ef5a6135f1fd6a02593eedc869c6d41d934aef8", Class: publicClassName})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if got := builder.sent(); len(got) != 2 {
+		t.Errorf("Shutdown() sent %d transfers, want 2", len(got))
+	}
+}
+
+func TestShutdownTwice(t *testing.T) {
+	s := newTestServer(t, &mockTxBuilder{}, testConfig())
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.Shutdown(context.Background()); err != nil {
+			t.Fatalf("Shutdown() #%d error = %v", i+1, err)
+		}
+	}
+}
+
+type closingTxBuilder struct {
+	mockTxBuilder
+	closed int32
+}
+
+func (c *closingTxBuilder) Close() {
+	atomic.AddInt32(&c.closed, 1)
+}
+
+func TestShutdownClosesProviders(t *testing.T) {
+	builder, sepolia := &closingTxBuilder{}, &closingTxBuilder{}
+	s := newTestServer(t, builder, testConfig())
+	s.AddNetwork("sepolia", newTestServer(t, sepolia, testConfig()))
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if builder.closed != 1 || sepolia.closed != 1 {
+		t.Errorf("closed = %d and %d, want the providers of every network closed once", builder.closed, sepolia.closed)
+	}
+}
+
+func TestShutdownPersistsQueue(t *testing.T) {
+	queueFile := filepath.Join(t.TempDir(), "queue.json")
+	address := "0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B"
+
+	cfg := testConfig()
+	cfg.QueueFile = queueFile
+	s := newTestServer(t, &mockTxBuilder{}, cfg)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	s.queue.push(&queueItem{Address: address, Class: publicClassName})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.Shutdown(ctx)
+
+	restarted := newTestServer(t, &mockTxBuilder{}, cfg)
+	if err := restarted.restoreQueue(); err != nil {
+		t.Fatalf("restoreQueue() error = %v", err)
+	}
+	if item, ok := restarted.queue.pop(); !ok || item.Address != address {
+		t.Errorf("restoreQueue() did not restore %s", address)
+	}
+}
+
+func TestFailedStartKeepsQueue(t *testing.T) {
+	dir := t.TempDir()
+	queueFile := filepath.Join(dir, "queue.json")
+	os.WriteFile(queueFile, []byte(`[{"address":"0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B","class":"public","amount":1}]`), 0600)
+	notSocket := filepath.Join(dir, "metrics.sock")
+	os.WriteFile(notSocket, nil, 0600)
+
+	cfg := testConfig()
+	cfg.QueueFile = queueFile
+	cfg.Metrics.Addr = "unix:" + notSocket
+	if err := newTestServer(t, &mockTxBuilder{}, cfg).Start(context.Background()); err == nil {
+		t.Fatal("Start() error = nil, want the metrics listener to fail")
+	}
+	if _, err := os.Stat(queueFile); err != nil {
+		t.Fatalf("queue file after failed start: %v", err)
+	}
+
+	cfg.Metrics.Addr = ""
+	s := newTestServer(t, &mockTxBuilder{}, cfg)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer s.Shutdown(context.Background())
+	if got := s.tracker.recent(10); len(got) != 1 {
+		t.Errorf("restored %d claims, want 1", len(got))
+	}
+}
+
+func TestV1Routes(t *testing.T) {
+	s := newTestServer(t, &mockTxBuilder{}, testConfig())
+	router := s.setupRouter()
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.RemoteAddr = "192.0.2.1:1234"
+		rec := httptest.NewRecorder()
+		negroni.New(negroni.Wrap(router)).ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("POST", "/api/v1/claim", `{"address":"0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B"}`)
+	var claim claimStatusResponse
+	if err := json.NewDecoder(rec.Body).Decode(&claim); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("claim status = %d, error = %v", rec.Code, err)
+	}
+	if claim.Status != statusBroadcast || claim.TxHash == "" || claim.Queued {
+		t.Errorf("claim = %+v, want broadcast with tx hash", claim)
+	}
+
+	rec = serve("GET", "/api/v1/claim/"+claim.ID, "")
+	var status claimStatusResponse
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil || status.ID != claim.ID {
+		t.Errorf("claim lookup = %+v, error = %v", status, err)
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		status     int
+		code       ErrorCode
+		retryAfter bool
+	}{
+		{name: "rate limited", body: `{"address":"0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B"}`, status: http.StatusTooManyRequests, code: ErrRateLimited, retryAfter: true},
+		{name: "invalid address", body: `{"address":"0xinvalid"}`, status: http.StatusBadRequest, code: ErrInvalidAddress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve("POST", "/api/v1/claim", tt.body)
+			var resp errorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != tt.status || resp.Code != tt.code || (resp.RetryAfter > 0) != tt.retryAfter {
+				t.Errorf("response = %d %+v, want %d %s", rec.Code, resp, tt.status, tt.code)
+			}
+		})
+	}
+
+	rec = serve("GET", "/api/v1/openapi.json", "")
+	var spec map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&spec); err != nil || spec["openapi"] == nil {
+		t.Errorf("openapi.json is not an OpenAPI document: %v", err)
+	}
+}
+
+func TestNetworkRoutes(t *testing.T) {
+	s := newTestServer(t, &mockTxBuilder{}, testConfig())
+	sepolia := &mockTxBuilder{}
+	cfg := testConfig()
+	cfg.Faucet.Name = "Sepolia"
+	cfg.Faucet.Amount = 3
+	s.AddNetwork("sepolia", newTestServer(t, sepolia, cfg))
+	router := s.setupRouter()
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.RemoteAddr = "192.0.2.1:1234"
+		rec := httptest.NewRecorder()
+		negroni.New(negroni.Wrap(router)).ServeHTTP(rec, req)
+		return rec
+	}
+
+	var networks []networkResponse
+	if err := json.NewDecoder(serve("GET", "/api/networks", "").Body).Decode(&networks); err != nil {
+		t.Fatal(err)
+	}
+	want := []networkResponse{{Network: "testnet", Path: "/api"}, {ID: "sepolia", Network: "Sepolia", Path: "/api/sepolia"}}
+	if !reflect.DeepEqual(networks, want) {
+		t.Errorf("networks = %+v, want %+v", networks, want)
+	}
+
+	for _, path := range []string{"/api/sepolia/info", "/api/v1/sepolia/info"} {
+		var info infoResponse
+		if err := json.NewDecoder(serve("GET", path, "").Body).Decode(&info); err != nil || info.Network != "Sepolia" || info.Payout != "3" {
+			t.Errorf("%s = %+v, error = %v", path, info, err)
+		}
+	}
+
+	// Each network has its own limiter and signer
+	body := `{"address":"0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B"}`
+	for _, path := range []string{"/api/claim", "/api/sepolia/claim"} {
+		if rec := serve("POST", path, body); rec.Code != http.StatusOK {
+			t.Errorf("POST %s status = %d: %s", path, rec.Code, rec.Body.String())
+		}
+	}
+	if rec := serve("POST", "/api/v1/sepolia/claim", body); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("second claim status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if len(sepolia.sent()) != 1 {
+		t.Errorf("sepolia transfers = %v, want one", sepolia.sent())
+	}
+}
+
+type chainVerifierFunc func(ctx context.Context) error
+
+func (f chainVerifierFunc) VerifyChainID(ctx context.Context) error {
+	return f(ctx)
+}
+
+func TestVerifyChain(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		paused bool
+	}{
+		{name: "same chain"},
+		{name: "provider unreachable", err: errors.New("connection refused")},
+		{name: "chain switched", err: fmt.Errorf("%w: provider is on chain 1, expected chain 5", chain.ErrChainIDMismatch), paused: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, &mockTxBuilder{}, testConfig())
+			s.verifyChain(chainVerifierFunc(func(context.Context) error { return tt.err }))
+			if s.isPaused() != tt.paused {
+				t.Errorf("paused = %v, want %v", s.isPaused(), tt.paused)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/chainflag/eth-faucet/internal/history"
+)
+
+var errStatsDisabled = &apiError{Code: ErrNotFound, Message: "Statistics are not enabled"}
+
+// recordHistory adds a sent claim to the claim history the statistics are
+// computed from. Claims are kept under the route name of the network, which
+// unlike its display name is unique and does not change on reload.
+func (s *Server) recordHistory(item *queueItem) {
+	if s.history == nil {
+		return
+	}
+	entry := history.Entry{
+		Network: s.networkLabel(),
+		Address: item.Address,
+		Amount:  item.Amount.String(),
+	}
+	if err := s.history.Add(entry); err != nil {
+		log.WithError(err).WithField("id", item.ID).Error("Failed to write the claim history")
+	}
+}
+
+func (s *Server) handleStats() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			http.NotFound(w, r)
+			return
+		}
+		if s.history == nil {
+			renderError(w, r, errStatsDisabled)
+			return
+		}
+		stats := s.history.Stats(s.networkLabel(), time.Now())
+		renderJSON(w, statsResponse{
+			Network:         s.cfg.Network(),
+			TotalClaims:     stats.Claims,
+			TotalAmount:     stats.Amount.String(),
+			UniqueAddresses: stats.Addresses,
+			Daily:           statsBuckets(stats.Daily),
+			Hourly:          statsBuckets(stats.Hourly),
+		}, http.StatusOK)
+	}
+}
+
+func statsBuckets(buckets []history.Bucket) []statsBucket {
+	out := make([]statsBucket, len(buckets))
+	for i, bucket := range buckets {
+		out[i] = statsBucket{Time: bucket.Time, Claims: bucket.Claims, Amount: bucket.Amount.String()}
+	}
+	return out
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/chainflag/eth-faucet/internal/history"
+)
+
+func TestStats(t *testing.T) {
+	s := newTestServer(t, &mockTxBuilder{}, testConfig())
+	router := s.setupRouter()
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/stats", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("stats without a history = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	claims, err := history.Open(filepath.Join(t.TempDir(), "claims.jsonl"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer claims.Close()
+	s.history = claims
+	for _, address := range []string{"0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B", "0x7ef5a6135f1fd6a02593eedc869c6d41d934aef8", "0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B"} {
+		if _, _, err := s.claim(context.Background(), address); err != nil {
+			t.Fatalf("claim() error = %v", err)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/stats", nil))
+	var stats statsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatalf("failed to decode stats: %v", err)
+	}
+	if stats.TotalClaims != 3 || stats.TotalAmount != "3000000000000000000" || stats.UniqueAddresses != 2 {
+		t.Errorf("stats = %+v, want 3 claims of 3 Ether to 2 addresses", stats)
+	}
+	for name, buckets := range map[string][]statsBucket{"daily": stats.Daily, "hourly": stats.Hourly} {
+		var total int
+		for _, bucket := range buckets {
+			total += bucket.Claims
+		}
+		if total != 3 {
+			t.Errorf("%s histogram = %+v, want 3 claims", name, buckets)
+		}
+	}
+
+	renamed := testConfig()
+	renamed.Faucet.Name = "renamed"
+	if err := s.Reload(renamed); err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/stats", nil))
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatalf("failed to decode stats: %v", err)
+	}
+	if stats.Network != "renamed" || stats.TotalClaims != 3 {
+		t.Errorf("stats after a rename = %+v, want the 3 claims under the new name", stats)
+	}
+}
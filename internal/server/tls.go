@@ -0,0 +1,143 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/chainflag/eth-faucet/internal/chain"
+)
+
+// eventWriteTimeout bounds each write of a long-lived response, like the
+// writes of the activity feed.
+const eventWriteTimeout = 10 * time.Second
+
+// keyPair serves a static certificate and reloads it when the certificate
+// file changes, e.g. after it was renewed by certbot.
+type keyPair struct {
+	mutex    sync.Mutex
+	certFile string
+	keyFile  string
+	modTime  time.Time
+	cert     *tls.Certificate
+}
+
+func loadKeyPair(certFile, keyFile string) (*keyPair, error) {
+	kp := &keyPair{certFile: certFile, keyFile: keyFile}
+	if err := kp.reload(); err != nil {
+		return nil, err
+	}
+	return kp, nil
+}
+
+func (kp *keyPair) reload() error {
+	info, err := os.Stat(kp.certFile)
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(kp.modTime) {
+		return nil
+	}
+	cert, err := tls.LoadX509KeyPair(kp.certFile, kp.keyFile)
+	if err != nil {
+		return err
+	}
+	kp.cert, kp.modTime = &cert, info.ModTime()
+	return nil
+}
+
+func (kp *keyPair) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	kp.mutex.Lock()
+	defer kp.mutex.Unlock()
+	// A renewal in progress may have written the certificate but not the key yet
+	if err := kp.reload(); err != nil {
+		log.WithError(err).Warn("Failed to reload the TLS certificate, serving the previous one")
+	}
+	return kp.cert, nil
+}
+
+// newTLSConfig returns the TLS settings of the listeners, or nil if TLS is
+// off. With ACME, it also returns the manager that answers the HTTP
+// challenges on the redirect listener.
+func newTLSConfig(cfg TLSConfig) (*tls.Config, *autocert.Manager, error) {
+	switch {
+	case cfg.CertFile != "":
+		kp, err := loadKeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: kp.GetCertificate,
+			// HTTP/2 is left out since its write timeout would cut off event streams
+			NextProtos: []string{"http/1.1"},
+		}, nil, nil
+	case cfg.Domains != "":
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			Cache:      autocert.DirCache(cfg.CacheDir),
+			HostPolicy: autocert.HostWhitelist(chain.SplitList(cfg.Domains)...),
+			Email:      cfg.Email,
+			Client:     &acme.Client{DirectoryURL: cfg.Directory},
+		}
+		return &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: manager.GetCertificate,
+			NextProtos:     []string{"http/1.1", acme.ALPNProto},
+		}, manager, nil
+	default:
+		return nil, nil, nil
+	}
+}
+
+// redirectHTTPS redirects requests to the same path on the HTTPS port.
+func redirectHTTPS(port int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+		if port != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(port))
+		}
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	})
+}
+
+type connContextKey struct{}
+
+// newHTTPServer returns a server with the configured timeouts. The connection
+// is kept in the request context so that long-lived responses can extend
+// their write deadline.
+func (s *Server) newHTTPServer(handler http.Handler) *http.Server {
+	timeouts := s.cfg.HTTP
+	return &http.Server{
+		Handler:           handler,
+		ReadTimeout:       time.Duration(timeouts.ReadTimeout) * time.Second,
+		ReadHeaderTimeout: time.Duration(timeouts.ReadHeaderTimeout) * time.Second,
+		WriteTimeout:      time.Duration(timeouts.WriteTimeout) * time.Second,
+		IdleTimeout:       time.Duration(timeouts.IdleTimeout) * time.Second,
+		ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+			return context.WithValue(ctx, connContextKey{}, c)
+		},
+	}
+}
+
+// extendWriteDeadline allows the next write of a long-lived response to take
+// eventWriteTimeout, instead of the write timeout counted from the start of
+// the request.
+func extendWriteDeadline(ctx context.Context) {
+	if conn, ok := ctx.Value(connContextKey{}).(net.Conn); ok {
+		conn.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+	}
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chainflag/eth-faucet/internal/chain"
+)
+
+// writeCertificate writes a self-signed certificate for 127.0.0.1.
+func writeCertificate(t *testing.T) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certFile, keyFile
+}
+
+func TestTLSEventStream(t *testing.T) {
+	cfg := testConfig()
+	cfg.TLS.CertFile, cfg.TLS.KeyFile = writeCertificate(t)
+	cfg.HTTP.WriteTimeout = 1
+	s := newTestServer(t, &mockTxBuilder{}, cfg)
+	tlsConfig, _, err := newTLSConfig(cfg.TLS)
+	if err != nil {
+		t.Fatalf("newTLSConfig() error = %v", err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := s.newHTTPServer(s.setupRouter())
+	go srv.Serve(tls.NewListener(ln, tlsConfig))
+	defer srv.Close()
+
+	item := &queueItem{ID: "claim", Address: "0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B", Class: publicClassName, Amount: chain.EtherToWei(1)}
+	s.tracker.report(item, statusBroadcast, "0x01", nil)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	resp, err := client.Get("https://" + ln.Addr().String() + "/api/v1/claim/claim/events")
+	if err != nil {
+		t.Fatalf("GET over TLS error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	// The update is sent after the write timeout of the request has passed
+	time.AfterFunc(1500*time.Millisecond, func() { s.tracker.reportBlock(item.ID, statusConfirmed, 7, nil) })
+	var events int
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "data: ") {
+			events++
+		}
+	}
+	if events != 2 {
+		t.Errorf("received %d events, want the broadcast and the confirmation", events)
+	}
+}
+
+func TestRedirectHTTPS(t *testing.T) {
+	tests := []struct {
+		port int
+		host string
+		want string
+	}{
+		{port: 443, host: "faucet.example.com", want: "https://faucet.example.com/api/info?x=1"},
+		{port: 443, host: "faucet.example.com:80", want: "https://faucet.example.com/api/info?x=1"},
+		{port: 8443, host: "faucet.example.com:8080", want: "https://faucet.example.com:8443/api/info?x=1"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/api/info?x=1", nil)
+		req.Host = tt.host
+		rec := httptest.NewRecorder()
+		redirectHTTPS(tt.port).ServeHTTP(rec, req)
+		if got := rec.Header().Get("Location"); rec.Code != http.StatusMovedPermanently || got != tt.want {
+			t.Errorf("redirect of %s = %d %s, want %s", tt.host, rec.Code, got, tt.want)
+		}
+	}
+}
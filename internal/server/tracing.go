@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/urfave/negroni"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer is a no-op until a tracer provider is installed, see cmd/tracing.go.
+var tracer = otel.Tracer("github.com/chainflag/eth-faucet/internal/server")
+
+// traceRequest starts a span for every HTTP request, continuing the trace of
+// the client if it sent a traceparent header.
+func traceRequest(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := tracer.Start(ctx, "HTTP "+r.Method, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+		semconv.HTTPMethodKey.String(r.Method),
+		semconv.HTTPTargetKey.String(r.URL.Path),
+	))
+	defer span.End()
+	if id := requestIDFromContext(ctx); id != "" {
+		span.SetAttributes(attribute.String("http.request_id", id))
+	}
+
+	next(w, r.WithContext(ctx))
+	status := w.(negroni.ResponseWriter).Status()
+	span.SetAttributes(semconv.HTTPStatusCodeKey.Int(status))
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+}
+
+// endSpan records a failure on the span before ending it.
+func endSpan(span trace.Span, err *apiError) {
+	if err != nil {
+		span.SetStatus(codes.Error, string(err.Code))
+	}
+	span.End()
+}
+
+// injectTrace returns the trace context of ctx in a form that can be stored
+// with a queued item.
+func injectTrace(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	return carrier
+}
+
+// extractTrace continues the trace a queued item was claimed in.
+func extractTrace(ctx context.Context, item *queueItem) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(item.Trace))
+}
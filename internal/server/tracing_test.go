@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestQueueTraceContext(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+
+	s := newTestServer(t, &mockTxBuilder{}, testConfig())
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "claim")
+	// Hold the lock so that the claim is queued
+	s.mutex.Lock()
+	item, _, err := s.claim(ctx, "0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B")
+	if err != nil {
+		t.Fatalf("claim() error = %v", err)
+	}
+	parent.End()
+	if item.Trace["traceparent"] == "" {
+		t.Fatalf("queued item trace = %v, want a traceparent", item.Trace)
+	}
+	s.drainQueue(context.Background())
+	s.mutex.Unlock()
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	enqueue, transfer := spans["queue.enqueue"], spans["queue.transfer"]
+	if enqueue == nil || transfer == nil {
+		t.Fatalf("recorded spans %v, want queue.enqueue and queue.transfer", spans)
+	}
+	if transfer.SpanContext().TraceID() != parent.SpanContext().TraceID() || transfer.Parent().SpanID() != enqueue.SpanContext().SpanID() {
+		t.Errorf("queue.transfer is not continuing the trace of the claim")
+	}
+	if !trace.SpanContextFromContext(extractTrace(context.Background(), item)).IsRemote() {
+		t.Errorf("extractTrace() does not restore the claim's span context")
+	}
+}
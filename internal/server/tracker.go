@@ -0,0 +1,188 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	statusQueued    = "queued"
+	statusBroadcast = "broadcast"
+	statusMined     = "mined"
+	statusConfirmed = "confirmed"
+	statusFailed    = "failed"
+	statusCancelled = "cancelled"
+
+	trackerCapacity = 1000
+
+	// allClaims subscribes to the changes of every claim
+	allClaims = ""
+)
+
+type claimRecord struct {
+	ID          string    `json:"id"`
+	Address     string    `json:"address"`
+	Class       string    `json:"class"`
+	Amount      string    `json:"amount"`
+	Batch       string    `json:"batch,omitempty"`
+	Status      string    `json:"status"`
+	TxHash      string    `json:"txHash,omitempty"`
+	BlockNumber uint64    `json:"blockNumber,omitempty"`
+	Code        ErrorCode `json:"code,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// done reports whether the claim will not change anymore.
+func (c *claimRecord) done() bool {
+	return c.Status == statusConfirmed || c.Status == statusFailed || c.Status == statusCancelled
+}
+
+// claimTracker keeps the state of the most recent claims in memory and
+// notifies subscribers of their changes.
+type claimTracker struct {
+	mutex       sync.RWMutex
+	capacity    int
+	order       []string
+	records     map[string]*claimRecord
+	subscribers map[string][]chan claimRecord
+	onChange    func(claimRecord)
+}
+
+func newClaimTracker(capacity int) *claimTracker {
+	return &claimTracker{
+		capacity:    capacity,
+		records:     make(map[string]*claimRecord),
+		subscribers: make(map[string][]chan claimRecord),
+	}
+}
+
+// report records a status change of the claim, adding it if it is not tracked yet.
+func (t *claimTracker) report(item *queueItem, status, txHash string, err *apiError) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	now := time.Now()
+	record, ok := t.records[item.ID]
+	if !ok {
+		record = &claimRecord{
+			ID:        item.ID,
+			Address:   item.Address,
+			Class:     item.Class,
+			Amount:    item.Amount.String(),
+			Batch:     item.Batch,
+			CreatedAt: now,
+		}
+		t.records[item.ID] = record
+		t.order = append(t.order, item.ID)
+		if len(t.order) > t.capacity {
+			delete(t.records, t.order[0])
+			t.order = t.order[1:]
+		}
+	}
+	record.Status = status
+	record.UpdatedAt = now
+	if txHash != "" {
+		record.TxHash = txHash
+	}
+	if err != nil {
+		record.Code, record.Error = err.Code, err.Message
+	}
+	t.notify(record)
+}
+
+// reportBlock records the inclusion of a broadcast claim in a block.
+func (t *claimTracker) reportBlock(id, status string, blockNumber uint64, err *apiError) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	record, ok := t.records[id]
+	if !ok || (record.Status == status && record.BlockNumber == blockNumber) {
+		return
+	}
+	record.Status = status
+	record.BlockNumber = blockNumber
+	record.UpdatedAt = time.Now()
+	if err != nil {
+		record.Code, record.Error = err.Code, err.Message
+	}
+	t.notify(record)
+}
+
+// notify sends the record to its subscribers. Subscribers that fall behind
+// miss intermediate updates. The caller must hold t.mutex.
+func (t *claimTracker) notify(record *claimRecord) {
+	if t.onChange != nil {
+		t.onChange(*record)
+	}
+	for _, id := range []string{record.ID, allClaims} {
+		for _, ch := range t.subscribers[id] {
+			select {
+			case ch <- *record:
+			default:
+			}
+		}
+	}
+}
+
+// subscribe returns a channel receiving every change of the claim, or of all
+// claims for allClaims, and a function to cancel the subscription.
+func (t *claimTracker) subscribe(id string) (<-chan claimRecord, func()) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	ch := make(chan claimRecord, 8)
+	t.subscribers[id] = append(t.subscribers[id], ch)
+	return ch, func() {
+		t.mutex.Lock()
+		defer t.mutex.Unlock()
+		subs := t.subscribers[id]
+		for i := range subs {
+			if subs[i] == ch {
+				subs = append(subs[:i], subs[i+1:]...)
+				break
+			}
+		}
+		if len(subs) == 0 {
+			delete(t.subscribers, id)
+		} else {
+			t.subscribers[id] = subs
+		}
+	}
+}
+
+func (t *claimTracker) get(id string) (claimRecord, bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	record, ok := t.records[id]
+	if !ok {
+		return claimRecord{}, false
+	}
+	return *record, true
+}
+
+// pending returns the claims that were broadcast after since but are not confirmed yet.
+func (t *claimTracker) pending(since time.Time) []claimRecord {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	var records []claimRecord
+	for _, id := range t.order {
+		record := t.records[id]
+		if (record.Status == statusBroadcast || record.Status == statusMined) && record.CreatedAt.After(since) {
+			records = append(records, *record)
+		}
+	}
+	return records
+}
+
+// recent returns up to limit records, newest first.
+func (t *claimTracker) recent(limit int) []claimRecord {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	if limit <= 0 || limit > len(t.order) {
+		limit = len(t.order)
+	}
+	records := make([]claimRecord, 0, limit)
+	for i := len(t.order) - 1; i >= 0 && len(records) < limit; i-- {
+		records = append(records, *t.records[t.order[i]])
+	}
+	return records
+}